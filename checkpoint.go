@@ -0,0 +1,399 @@
+package meduce
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/djordje200179/extendedlibrary/misc"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+const (
+	// checkpointFile is the name of the file in CheckpointDir
+	// that describes persisted progress of the process.
+	checkpointFile = "meduce-checkpoint.json"
+	// runFilesPattern matches names of files with spilled runs.
+	runFilesPattern = "meduce-run-*"
+
+	defaultCheckpointInterval = 100_000
+)
+
+// ErrCheckpointSource is returned by Run when the source
+// has fewer records than the checkpoint that is resumed.
+var ErrCheckpointSource = errors.New("meduce: source has fewer records than the checkpoint")
+
+// A checkpoint is the persisted progress of a process.
+//
+// Runs contain mapped data of the first Records records of the source.
+// The first Groups reduced groups were collected in order,
+// and Output is the position of the collector after them.
+type checkpoint struct {
+	Records int      `json:"records"`
+	Mapped  bool     `json:"mapped"`
+	Runs    []string `json:"runs"`
+	Pairs   int      `json:"pairs"`
+
+	Groups int   `json:"groups"`
+	Output int64 `json:"output"`
+}
+
+// A checkpointEpoch is a part of the source that is mapped between two checkpoints.
+// Every mapping thread marks the epoch as finished once it spilled its data.
+type checkpointEpoch[KeyIn, ValueIn any] struct {
+	records  chan misc.Pair[KeyIn, ValueIn]
+	finished *sync.WaitGroup
+}
+
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) checkpointInterval() int {
+	if process.CheckpointInterval <= 0 {
+		return defaultCheckpointInterval
+	}
+
+	return process.CheckpointInterval
+}
+
+// loadCheckpoint reads the checkpoint from CheckpointDir, if it exists,
+// and removes runs that were spilled after it was persisted.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) loadCheckpoint() error {
+	dir := process.CheckpointDir
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, checkpointFile))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		process.checkpoint = checkpoint{}
+	case err != nil:
+		return err
+	default:
+		if err := json.Unmarshal(data, &process.checkpoint); err != nil {
+			return fmt.Errorf("meduce: invalid checkpoint: %w", err)
+		}
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, runFilesPattern))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		if !slices.Contains(process.checkpoint.Runs, filepath.Base(path)) {
+			_ = os.Remove(path)
+		}
+	}
+
+	if process.checkpoint.Records > 0 {
+		if process.Logger != nil {
+			process.Logger.Printf(
+				"Process %s: resuming after %d records and %d groups\n",
+				process.label(), process.checkpoint.Records, process.checkpoint.Groups,
+			)
+		}
+		process.logEvent(
+			process.LogLevels.process(), LogCheckpointResumed,
+			slog.Int("records", process.checkpoint.Records),
+			slog.Bool("mapped", process.checkpoint.Mapped),
+			slog.Int("groups", process.checkpoint.Groups),
+		)
+	}
+
+	return nil
+}
+
+// saveCheckpoint persists the checkpoint to CheckpointDir.
+// The file is replaced atomically, so a crash leaves the previous checkpoint.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) saveCheckpoint(state checkpoint) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(process.CheckpointDir, checkpointFile+".*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		_ = os.Remove(file.Name())
+		return err
+	}
+
+	if err := os.Rename(file.Name(), filepath.Join(process.CheckpointDir, checkpointFile)); err != nil {
+		_ = os.Remove(file.Name())
+		return err
+	}
+
+	process.checkpoint = state
+
+	process.logEvent(
+		process.LogLevels.thread(), LogCheckpointSaved,
+		slog.Int("records", state.Records),
+		slog.Bool("mapped", state.Mapped),
+		slog.Int("runs", len(state.Runs)),
+		slog.Int("groups", state.Groups),
+	)
+
+	return nil
+}
+
+// removeCheckpoint removes the checkpoint and runs that it lists
+// after the process finished successfully, so that the next run starts from scratch.
+// Failed or cancelled processes keep them, to be resumed.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) removeCheckpoint(ctx context.Context) {
+	if process.CheckpointDir == "" || ctx.Err() != nil || process.Err() != nil {
+		return
+	}
+
+	err := os.Remove(filepath.Join(process.CheckpointDir, checkpointFile))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		process.fail(err)
+		return
+	}
+
+	for i := range process.mappingThreads {
+		for _, path := range process.mappingThreads[i].runs {
+			_ = os.Remove(path)
+		}
+	}
+}
+
+// feedEpochs passes the source to mapping threads in epochs of CheckpointInterval records.
+// After every epoch, when all mapping threads spilled their data,
+// a checkpoint is persisted, so that a restarted process continues after it.
+//
+// Records that were mapped by the resumed checkpoint are skipped.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) feedEpochs(ctx context.Context) {
+	defer func() {
+		for i := range process.mappingThreads {
+			close(process.mappingThreads[i].epochs)
+		}
+	}()
+
+	state := process.checkpoint
+	if state.Mapped {
+		process.Source.stop()
+		return
+	}
+
+	for range state.Records {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-process.Source.records:
+			if !ok {
+				process.fail(ErrCheckpointSource)
+				return
+			}
+		}
+	}
+
+	for !state.Mapped {
+		var epochFinished sync.WaitGroup
+		epochFinished.Add(len(process.mappingThreads))
+
+		epoch := checkpointEpoch[KeyIn, ValueIn]{
+			records:  make(chan misc.Pair[KeyIn, ValueIn], cap(process.Source.records)),
+			finished: &epochFinished,
+		}
+		for i := range process.mappingThreads {
+			process.mappingThreads[i].epochs <- epoch
+		}
+
+		recordsCount, mapped := feedEpoch(ctx, process.Source.records, epoch.records, process.checkpointInterval())
+		close(epoch.records)
+		epochFinished.Wait()
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		state.Records += recordsCount
+		state.Mapped = mapped
+		state.Runs, state.Pairs = process.checkpointRuns()
+
+		if err := process.saveCheckpoint(state); err != nil {
+			process.fail(err)
+			return
+		}
+
+		process.removeCompactedRuns()
+	}
+}
+
+// compactRuns merges the smallest runs of the mapping thread into one,
+// when the thread has more than its share of maxOpenRuns of them,
+// so that the number of runs in CheckpointDir stays bounded.
+//
+// Merged runs are still listed by the last checkpoint,
+// so they are removed only after the next one is persisted.
+func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) compactRuns() error {
+	limit := max(maxOpenRuns/len(thread.mappingThreads), 2)
+	if len(thread.runs) <= limit {
+		return nil
+	}
+
+	sizes := make(map[string]int64, len(thread.runs))
+	for _, path := range thread.runs {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		sizes[path] = info.Size()
+	}
+
+	slices.SortFunc(thread.runs, func(first, second string) int {
+		return cmp.Compare(sizes[first], sizes[second])
+	})
+
+	compacted := thread.runs[:limit]
+
+	path, err := thread.mergeRunFiles(thread.CheckpointDir, compacted)
+	if err != nil {
+		return err
+	}
+
+	thread.compactedRuns = append(thread.compactedRuns, compacted...)
+	thread.runs = append(slices.Clone(thread.runs[limit:]), path)
+
+	return nil
+}
+
+// removeCompactedRuns removes runs that were merged by mapping
+// threads and are not listed by the last checkpoint anymore.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) removeCompactedRuns() {
+	for i := range process.mappingThreads {
+		for _, path := range process.mappingThreads[i].compactedRuns {
+			_ = os.Remove(path)
+		}
+
+		process.mappingThreads[i].compactedRuns = nil
+	}
+}
+
+// feedEpoch passes at most limit records from the source to the epoch.
+// It returns the number of passed records and reports whether the source was exhausted.
+func feedEpoch[KeyIn, ValueIn any](
+	ctx context.Context,
+	source <-chan misc.Pair[KeyIn, ValueIn],
+	records chan<- misc.Pair[KeyIn, ValueIn],
+	limit int,
+) (int, bool) {
+	for recordsCount := 0; recordsCount < limit; recordsCount++ {
+		select {
+		case <-ctx.Done():
+			return recordsCount, false
+		case pair, ok := <-source:
+			if !ok {
+				return recordsCount, true
+			}
+
+			select {
+			case records <- pair:
+			case <-ctx.Done():
+				return recordsCount, false
+			}
+		}
+	}
+
+	return limit, false
+}
+
+// checkpointRuns returns names of runs of all mapping threads
+// and the number of key-value pairs in them.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) checkpointRuns() ([]string, int) {
+	var runs []string
+	var pairsCount int
+
+	for i := range process.mappingThreads {
+		for _, path := range process.mappingThreads[i].runs {
+			runs = append(runs, filepath.Base(path))
+		}
+
+		pairsCount += process.mappingThreads[i].spilledCount
+	}
+
+	return runs, pairsCount
+}
+
+// resumeRuns returns paths of runs of the resumed checkpoint.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) resumeRuns() []string {
+	paths := make([]string, len(process.checkpoint.Runs))
+	for i, name := range process.checkpoint.Runs {
+		paths[i] = filepath.Join(process.CheckpointDir, name)
+	}
+
+	return paths
+}
+
+// resumableCollector returns the collector if its output can be resumed.
+// Output of processes that pass pairs to linked processes can't be resumed.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) resumableCollector() (ResumableCollector, bool) {
+	if process.CheckpointDir == "" || len(process.links) > 0 {
+		return nil, false
+	}
+
+	collector, ok := any(process.Collector).(ResumableCollector)
+	return collector, ok
+}
+
+// resumeCollector discards output that was collected after the resumed checkpoint.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) resumeCollector() error {
+	collector, ok := process.resumableCollector()
+	if !ok {
+		return nil
+	}
+
+	if !process.OrderedOutput {
+		return collector.Resume(0)
+	}
+
+	return collector.Resume(process.checkpoint.Output)
+}
+
+// skippedGroups returns the number of groups that were
+// already collected in order before the process was resumed.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) skippedGroups() int {
+	if _, ok := process.resumableCollector(); !ok || !process.OrderedOutput {
+		return 0
+	}
+
+	return process.checkpoint.Groups
+}
+
+// checkpointGroups persists the number of groups that were collected in order.
+// It is called by the reorder buffer every CheckpointInterval groups.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) checkpointGroups(groupsCount int) error {
+	collector, _ := process.resumableCollector()
+
+	position, err := collector.Checkpoint()
+	if err != nil {
+		return err
+	}
+
+	state := process.checkpoint
+	state.Groups = groupsCount
+	state.Output = position
+
+	return process.saveCheckpoint(state)
+}
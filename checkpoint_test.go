@@ -0,0 +1,145 @@
+package meduce_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/djordje200179/meduce"
+	"github.com/djordje200179/meduce/collectors"
+	"github.com/djordje200179/meduce/sources"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCheckpointResume(t *testing.T) {
+	dir := t.TempDir()
+
+	newConfig := func(mapped func(value int)) meduce.Config[int, int, int, int] {
+		return meduce.Config[int, int, int, int]{
+			Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+				mapped(value)
+				emit(value%100, 1)
+			},
+			Reducer:            sum,
+			Source:             sources.NewSliceSource(numbers(20_000)),
+			Collector:          collectors.NewMapCollector[int, int](),
+			MapWorkers:         2,
+			CheckpointDir:      dir,
+			CheckpointInterval: 100,
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	config := newConfig(func(value int) {
+		if value == 15_000 {
+			cancel()
+		}
+	})
+
+	if err := meduce.NewDefaultProcess(config).RunContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("RunContext() = %v, want %v", err, context.Canceled)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "meduce-checkpoint.json")); err != nil {
+		t.Fatalf("checkpoint was not saved: %v", err)
+	}
+
+	// Runs are compacted, so their number stays bounded.
+	if runs, _ := filepath.Glob(filepath.Join(dir, "meduce-run-*")); len(runs) > 2*64 {
+		t.Errorf("%d runs in checkpoint directory, want at most %d", len(runs), 2*64)
+	}
+
+	var mapped atomic.Int32
+	collector := collectors.NewMapCollector[int, int]()
+
+	config = newConfig(func(int) { mapped.Add(1) })
+	config.Collector = collector
+
+	if err := meduce.NewDefaultProcess(config).Run(); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+
+	for key := range 100 {
+		if collector[key] != 200 {
+			t.Errorf("value of key %d = %d, want 200", key, collector[key])
+		}
+	}
+
+	if mapped.Load() >= 20_000 {
+		t.Errorf("resumed process mapped all %d records again", mapped.Load())
+	}
+
+	if entries, _ := os.ReadDir(dir); len(entries) > 0 {
+		t.Errorf("checkpoint directory has %d entries after the process finished", len(entries))
+	}
+}
+
+func TestCheckpointOrderedOutput(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "output.txt")
+
+	run := func(ctx context.Context, reduced func(key int)) error {
+		collector, err := collectors.NewResumableFileCollector[int, int](output)
+		if err != nil {
+			t.Fatalf("NewResumableFileCollector() = %v", err)
+		}
+
+		process := meduce.NewDefaultProcess(meduce.Config[int, int, int, int]{
+			Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+				emit(value%1000, 1)
+			},
+			Reducer: func(key int, values []int) int {
+				reduced(key)
+				return sum(key, values)
+			},
+			Source:             sources.NewSliceSource(numbers(10_000)),
+			Collector:          collector,
+			DisableCombining:   true,
+			OrderedOutput:      true,
+			CheckpointDir:      filepath.Join(dir, "checkpoints"),
+			CheckpointInterval: 100,
+		})
+
+		return process.RunContext(ctx)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := run(ctx, func(key int) {
+		if key == 500 {
+			cancel()
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("RunContext() = %v, want %v", err, context.Canceled)
+	}
+
+	var reduced atomic.Int32
+	if err := run(context.Background(), func(int) { reduced.Add(1) }); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+
+	if reduced.Load() >= 1000 {
+		t.Errorf("resumed process reduced all %d groups again", reduced.Load())
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected strings.Builder
+	for key := range 1000 {
+		fmt.Fprintf(&expected, "%v: %v\n", key, 10)
+	}
+
+	if string(data) != expected.String() {
+		t.Errorf("output of the resumed process differs from expected")
+	}
+}
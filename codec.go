@@ -0,0 +1,58 @@
+package meduce
+
+import (
+	"encoding/gob"
+	"io"
+)
+
+// A Codec is used to write keys or values to temporary files
+// and to read them back when mapped data doesn't fit into memory.
+type Codec[T any] interface {
+	NewEncoder(writer io.Writer) Encoder[T] // NewEncoder creates an encoder that writes to the writer
+	NewDecoder(reader io.Reader) Decoder[T] // NewDecoder creates a decoder that reads from the reader
+}
+
+// An Encoder writes values to an underlying stream.
+type Encoder[T any] interface {
+	Encode(value T) error
+}
+
+// A Decoder reads values from an underlying stream.
+//
+// Decode should return io.EOF when there are no more values.
+type Decoder[T any] interface {
+	Decode() (T, error)
+}
+
+// GobCodec is a Codec that uses encoding/gob to encode values.
+//
+// It can encode only exported fields of structs.
+// Zero value of GobCodec is a valid codec.
+type GobCodec[T any] struct{}
+
+func (GobCodec[T]) NewEncoder(writer io.Writer) Encoder[T] {
+	return gobEncoder[T]{gob.NewEncoder(writer)}
+}
+
+func (GobCodec[T]) NewDecoder(reader io.Reader) Decoder[T] {
+	return gobDecoder[T]{gob.NewDecoder(reader)}
+}
+
+type gobEncoder[T any] struct {
+	encoder *gob.Encoder
+}
+
+func (encoder gobEncoder[T]) Encode(value T) error {
+	return encoder.encoder.Encode(&value)
+}
+
+type gobDecoder[T any] struct {
+	decoder *gob.Decoder
+}
+
+func (decoder gobDecoder[T]) Decode() (T, error) {
+	var value T
+	err := decoder.decoder.Decode(&value)
+
+	return value, err
+}
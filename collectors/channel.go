@@ -11,16 +11,18 @@ func NewChannelCollector[KeyOut, ValueOut any](bufferSize int) ChannelCollector[
 	return make(chan misc.Pair[KeyOut, ValueOut], bufferSize)
 }
 
-func (collector ChannelCollector[KeyOut, ValueOut]) Init() {
-
+func (collector ChannelCollector[KeyOut, ValueOut]) Init() error {
+	return nil
 }
 
-func (collector ChannelCollector[KeyOut, ValueOut]) Collect(key KeyOut, value ValueOut) {
+func (collector ChannelCollector[KeyOut, ValueOut]) Collect(key KeyOut, value ValueOut) error {
 	collector <- misc.Pair[KeyOut, ValueOut]{key, value}
+	return nil
 }
 
-func (collector ChannelCollector[KeyOut, ValueOut]) Finalize() {
+func (collector ChannelCollector[KeyOut, ValueOut]) Finalize() error {
 	close(collector)
+	return nil
 }
 
 // Get returns the collected channel.
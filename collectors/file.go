@@ -1,11 +1,18 @@
 package collectors
 
 import (
+	"errors"
 	"fmt"
 	"github.com/djordje200179/meduce"
+	"io"
 	"os"
 )
 
+// ErrOutputTruncated is returned when a process resumes writing to a file
+// that is shorter than output of the checkpoint, like one that was
+// created by NewFileCollector instead of NewResumableFileCollector.
+var ErrOutputTruncated = errors.New("collectors: file is shorter than the resumed output")
+
 // Formatter is a function that formats key and value into a string.
 // It is used by FileCollector to format key and value before writing them to a file.
 type Formatter[KeyOut, ValueOut any] func(key KeyOut, value ValueOut) string
@@ -18,17 +25,17 @@ type FileCollector[KeyOut, ValueOut any] struct {
 
 // NewFileCollector creates a new FileCollector
 // that writes key-value pairs to a file at the given path.
-func NewFileCollector[KeyOut, ValueOut any](path string) FileCollector[KeyOut, ValueOut] {
+func NewFileCollector[KeyOut, ValueOut any](path string) (FileCollector[KeyOut, ValueOut], error) {
 	file, err := os.Create(path)
 	if err != nil {
-		panic(err)
+		return FileCollector[KeyOut, ValueOut]{}, err
 	}
 
 	collector := FileCollector[KeyOut, ValueOut]{
 		file: file,
 	}
 
-	return collector
+	return collector, nil
 }
 
 // NewFileCollectorWithFormatter creates a new FileCollector
@@ -37,10 +44,10 @@ func NewFileCollector[KeyOut, ValueOut any](path string) FileCollector[KeyOut, V
 func NewFileCollectorWithFormatter[KeyOut, ValueOut any](
 	path string,
 	formatter Formatter[KeyOut, ValueOut],
-) meduce.Collector[KeyOut, ValueOut] {
+) (meduce.Collector[KeyOut, ValueOut], error) {
 	file, err := os.Create(path)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	collector := FileCollector[KeyOut, ValueOut]{
@@ -48,14 +55,31 @@ func NewFileCollectorWithFormatter[KeyOut, ValueOut any](
 		formatter: formatter,
 	}
 
-	return collector
+	return collector, nil
 }
 
-func (collector FileCollector[KeyOut, ValueOut]) Init() {
+// NewResumableFileCollector creates a new FileCollector
+// that writes key-value pairs to a file at the given path.
+// Unlike NewFileCollector, it keeps contents of an existing file,
+// so that a process with a checkpoint can resume writing to it.
+func NewResumableFileCollector[KeyOut, ValueOut any](path string) (FileCollector[KeyOut, ValueOut], error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0o666)
+	if err != nil {
+		return FileCollector[KeyOut, ValueOut]{}, err
+	}
 
+	collector := FileCollector[KeyOut, ValueOut]{
+		file: file,
+	}
+
+	return collector, nil
 }
 
-func (collector FileCollector[KeyOut, ValueOut]) Collect(key KeyOut, value ValueOut) {
+func (collector FileCollector[KeyOut, ValueOut]) Init() error {
+	return nil
+}
+
+func (collector FileCollector[KeyOut, ValueOut]) Collect(key KeyOut, value ValueOut) error {
 	var line string
 	if collector.formatter != nil {
 		line = collector.formatter(key, value)
@@ -64,14 +88,37 @@ func (collector FileCollector[KeyOut, ValueOut]) Collect(key KeyOut, value Value
 	}
 
 	_, err := collector.file.WriteString(line)
-	if err != nil {
-		panic(err)
+	return err
+}
+
+func (collector FileCollector[KeyOut, ValueOut]) Finalize() error {
+	return collector.file.Close()
+}
+
+// Checkpoint syncs the file to disk and returns the current offset in it.
+func (collector FileCollector[KeyOut, ValueOut]) Checkpoint() (int64, error) {
+	if err := collector.file.Sync(); err != nil {
+		return 0, err
 	}
+
+	return collector.file.Seek(0, io.SeekCurrent)
 }
 
-func (collector FileCollector[KeyOut, ValueOut]) Finalize() {
-	err := collector.file.Close()
+// Resume truncates the file to the given offset and continues writing from it.
+func (collector FileCollector[KeyOut, ValueOut]) Resume(position int64) error {
+	info, err := collector.file.Stat()
 	if err != nil {
-		panic(err)
+		return err
 	}
+
+	if info.Size() < position {
+		return ErrOutputTruncated
+	}
+
+	if err := collector.file.Truncate(position); err != nil {
+		return err
+	}
+
+	_, err = collector.file.Seek(position, io.SeekStart)
+	return err
 }
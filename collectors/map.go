@@ -8,16 +8,17 @@ func NewMapCollector[KeyOut comparable, ValueOut any]() MapCollector[KeyOut, Val
 	return make(MapCollector[KeyOut, ValueOut])
 }
 
-func (collector MapCollector[KeyOut, ValueOut]) Init() {
-
+func (collector MapCollector[KeyOut, ValueOut]) Init() error {
+	return nil
 }
 
-func (collector MapCollector[KeyOut, ValueOut]) Collect(key KeyOut, value ValueOut) {
+func (collector MapCollector[KeyOut, ValueOut]) Collect(key KeyOut, value ValueOut) error {
 	collector[key] = value
+	return nil
 }
 
-func (collector MapCollector[KeyOut, ValueOut]) Finalize() {
-
+func (collector MapCollector[KeyOut, ValueOut]) Finalize() error {
+	return nil
 }
 
 // Get returns the collected map.
@@ -1,5 +1,11 @@
 package collectors
 
+import "errors"
+
+// ErrAlreadyCollected is returned by SingleValueCollector
+// when more than one key-value pair is collected.
+var ErrAlreadyCollected = errors.New("collectors: already collected a value")
+
 // SingleValueCollector is a collector that collects a single value.
 //
 // Zero value of SingleValueCollector is a valid collector.
@@ -16,22 +22,24 @@ func NewSingleValueCollector[KeyOut comparable, ValueOut any]() *SingleValueColl
 	return &SingleValueCollector[KeyOut, ValueOut]{}
 }
 
-func (collector *SingleValueCollector[KeyOut, ValueOut]) Init() {
-
+func (collector *SingleValueCollector[KeyOut, ValueOut]) Init() error {
+	return nil
 }
 
-func (collector *SingleValueCollector[KeyOut, ValueOut]) Collect(key KeyOut, value ValueOut) {
+func (collector *SingleValueCollector[KeyOut, ValueOut]) Collect(key KeyOut, value ValueOut) error {
 	if collector.set {
-		panic("Already collected a value")
+		return ErrAlreadyCollected
 	}
 
 	collector.set = true
 	collector.key = key
 	collector.value = value
-}
 
-func (collector *SingleValueCollector[KeyOut, ValueOut]) Finalize() {
+	return nil
+}
 
+func (collector *SingleValueCollector[KeyOut, ValueOut]) Finalize() error {
+	return nil
 }
 
 // Get returns the collected key-value pair.
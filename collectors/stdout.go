@@ -37,11 +37,12 @@ func NewStdoutCollectorWithFormatter[KeyOut, ValueOut any](
 	return collector
 }
 
-func (collector StdoutCollector[KeyOut, ValueOut]) Init() {
+func (collector StdoutCollector[KeyOut, ValueOut]) Init() error {
 	stdoutMutex.Lock()
+	return nil
 }
 
-func (collector StdoutCollector[KeyOut, ValueOut]) Collect(key KeyOut, value ValueOut) {
+func (collector StdoutCollector[KeyOut, ValueOut]) Collect(key KeyOut, value ValueOut) error {
 	var line string
 	if collector.formatter != nil {
 		line = collector.formatter(key, value)
@@ -49,9 +50,11 @@ func (collector StdoutCollector[KeyOut, ValueOut]) Collect(key KeyOut, value Val
 		line = fmt.Sprintf("%v: %v\n", key, value)
 	}
 
-	fmt.Print(line)
+	_, err := fmt.Print(line)
+	return err
 }
 
-func (collector StdoutCollector[KeyOut, ValueOut]) Finalize() {
+func (collector StdoutCollector[KeyOut, ValueOut]) Finalize() error {
 	stdoutMutex.Unlock()
+	return nil
 }
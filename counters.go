@@ -0,0 +1,103 @@
+package meduce
+
+import (
+	"math/bits"
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Counters are named counters that user functions can increment,
+// for example to count records that were skipped.
+//
+// They can be incremented from multiple threads at once without
+// contention, as every counter is split into multiple shards.
+// Values of the counters are returned in Stats and logged
+// when the process is finished.
+type Counters struct {
+	counters sync.Map
+}
+
+// NewCounters creates a new empty set of counters.
+func NewCounters() *Counters {
+	return &Counters{}
+}
+
+// Counter returns the counter with the given name,
+// creating it if it doesn't exist.
+//
+// Counters that are used often should be looked up once,
+// and then incremented directly.
+func (counters *Counters) Counter(name string) *Counter {
+	if counter, ok := counters.counters.Load(name); ok {
+		return counter.(*Counter)
+	}
+
+	counter, _ := counters.counters.LoadOrStore(name, newCounter())
+	return counter.(*Counter)
+}
+
+// Add adds delta to the counter with the given name.
+func (counters *Counters) Add(name string, delta int64) {
+	counters.Counter(name).Add(delta)
+}
+
+// Inc increments the counter with the given name.
+func (counters *Counters) Inc(name string) {
+	counters.Counter(name).Add(1)
+}
+
+// Values returns current values of all counters.
+func (counters *Counters) Values() map[string]int64 {
+	values := make(map[string]int64)
+
+	counters.counters.Range(func(name, counter any) bool {
+		values[name.(string)] = counter.(*Counter).Value()
+		return true
+	})
+
+	return values
+}
+
+// A Counter is a single named counter.
+type Counter struct {
+	shards []counterShard
+	mask   uint32
+}
+
+// counterShard is padded to the size of a cache line,
+// so that threads incrementing different shards don't slow each other.
+type counterShard struct {
+	value atomic.Int64
+	_     [56]byte
+}
+
+func newCounter() *Counter {
+	shardsCount := 1 << bits.Len(uint(runtime.GOMAXPROCS(0)-1))
+
+	return &Counter{
+		shards: make([]counterShard, shardsCount),
+		mask:   uint32(shardsCount - 1),
+	}
+}
+
+// Add adds delta to the counter.
+func (counter *Counter) Add(delta int64) {
+	counter.shards[rand.Uint32()&counter.mask].value.Add(delta)
+}
+
+// Inc increments the counter.
+func (counter *Counter) Inc() {
+	counter.Add(1)
+}
+
+// Value returns the current value of the counter.
+func (counter *Counter) Value() int64 {
+	var value int64
+	for i := range counter.shards {
+		value += counter.shards[i].value.Load()
+	}
+
+	return value
+}
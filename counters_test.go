@@ -0,0 +1,48 @@
+package meduce_test
+
+import (
+	"github.com/djordje200179/meduce"
+	"github.com/djordje200179/meduce/collectors"
+	"github.com/djordje200179/meduce/sources"
+	"testing"
+)
+
+func TestCounters(t *testing.T) {
+	counters := meduce.NewCounters()
+	odd := counters.Counter("odd")
+
+	job := meduce.NewDefaultJob(meduce.Config[int, int, int, int]{
+		Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+			if value%2 == 1 {
+				odd.Inc()
+				return
+			}
+
+			emit(value%10, 1)
+		},
+		Reducer:    sum,
+		Counters:   counters,
+		MapWorkers: 4,
+	})
+
+	// Every run of the job reports only its own counts.
+	for range 3 {
+		process := job.NewProcess(sources.NewSliceSource(numbers(100)), collectors.NewMapCollector[int, int]())
+		if err := process.Run(); err != nil {
+			t.Fatalf("Run() = %v", err)
+		}
+
+		if values := process.Stats().Counters; len(values) != 1 || values["odd"] != 50 {
+			t.Errorf("Stats().Counters = %v, want 50 odd numbers", values)
+		}
+	}
+
+	if value := counters.Counter("odd").Value(); value != 150 {
+		t.Errorf("counter odd = %d, want 150", value)
+	}
+
+	counters.Add("odd", 10)
+	if values := counters.Values(); values["odd"] != 160 {
+		t.Errorf("Values() = %v, want 160 odd numbers", values)
+	}
+}
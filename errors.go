@@ -0,0 +1,71 @@
+package meduce
+
+import "errors"
+
+// Errors that are returned by Run when the configuration is not valid.
+var (
+	ErrNoKeyComparator = errors.New("meduce: KeyComparator must be set")
+	ErrNoMapper        = errors.New("meduce: Mapper must be set")
+	ErrNoReducer       = errors.New("meduce: Reducer must be set")
+	ErrNoSource        = errors.New("meduce: Source must be set")
+	ErrNoCollector     = errors.New("meduce: Collector must be set")
+
+	ErrHashMemoryLimit   = errors.New("meduce: MemoryLimit is not supported with hash grouping")
+	ErrHashOrderedOutput = errors.New("meduce: OrderedOutput is not supported with hash grouping")
+	ErrHashCheckpoint    = errors.New("meduce: CheckpointDir is not supported with hash grouping")
+)
+
+// ErrAlreadyRun is returned by Run when the process was already run.
+var ErrAlreadyRun = errors.New("meduce: process was already run")
+
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) validate() error {
+	switch {
+	case process.KeyComparator == nil && process.hashGrouper == nil:
+		return ErrNoKeyComparator
+	case process.MemoryLimit > 0 && process.hashGrouper != nil:
+		return ErrHashMemoryLimit
+	case process.OrderedOutput && process.hashGrouper != nil:
+		return ErrHashOrderedOutput
+	case process.CheckpointDir != "" && process.hashGrouper != nil:
+		return ErrHashCheckpoint
+	case process.Mapper == nil && process.MapperCtx == nil && process.MapperFactory == nil:
+		return ErrNoMapper
+	case process.Reducer == nil && process.ReducerCtx == nil && process.ReducerFactory == nil:
+		return ErrNoReducer
+	case process.Source.records == nil:
+		return ErrNoSource
+	case process.Collector == nil && len(process.links) == 0:
+		return ErrNoCollector
+	default:
+		return nil
+	}
+}
+
+// fail records the first error that happened during the process
+// and aborts it, together with all processes linked to it.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) fail(err error) {
+	process.errMutex.Lock()
+	if process.err != nil {
+		process.errMutex.Unlock()
+		return
+	}
+
+	process.err = err
+	if process.cancel != nil {
+		process.cancel(err)
+	}
+	process.errMutex.Unlock()
+
+	for _, failLinked := range process.failLinked {
+		failLinked(err)
+	}
+}
+
+// Err returns the error that stopped the process,
+// or nil if the process has not failed.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) Err() error {
+	process.errMutex.Lock()
+	defer process.errMutex.Unlock()
+
+	return process.err
+}
@@ -0,0 +1,80 @@
+package meduce
+
+// A ThreadMapper is a mapper with state that belongs to a single mapping thread.
+//
+// Setup is called once before the thread maps its first record,
+// and Cleanup once after it has mapped and combined all of them,
+// so they can prepare and release resources (like compiled regular
+// expressions, opened files or scratch buffers) that Map uses.
+// Cleanup is called whenever Setup succeeded, even if the process failed.
+type ThreadMapper[KeyIn, ValueIn, KeyOut, ValueOut any] interface {
+	Setup(ctx *TaskContext) error
+	Map(ctx *TaskContext, key KeyIn, value ValueIn, emit Emitter[KeyOut, ValueOut])
+	Cleanup(ctx *TaskContext) error
+}
+
+// A ThreadAggregator is an aggregator with state
+// that belongs to a single reducing thread.
+//
+// Setup and Cleanup are called as for ThreadMapper.
+type ThreadAggregator[KeyOut, ValueMid, ValueOut any] interface {
+	Setup(ctx *TaskContext) error
+	Reduce(ctx *TaskContext, key KeyOut, values []ValueMid) ValueOut
+	Cleanup(ctx *TaskContext) error
+}
+
+// A ThreadReducer is a reducer with state that belongs to a single reducing thread.
+//
+// If it is used as combiner, mapping threads also get their own instances.
+type ThreadReducer[KeyOut, ValueOut any] = ThreadAggregator[KeyOut, ValueOut, ValueOut]
+
+// A MapperFactory creates a new mapper for every mapping thread.
+type MapperFactory[KeyIn, ValueIn, KeyOut, ValueOut any] func() ThreadMapper[KeyIn, ValueIn, KeyOut, ValueOut]
+
+// An AggregatorFactory creates a new aggregator for every reducing thread.
+type AggregatorFactory[KeyOut, ValueMid, ValueOut any] func() ThreadAggregator[KeyOut, ValueMid, ValueOut]
+
+// A ReducerFactory creates a new reducer for every reducing thread.
+type ReducerFactory[KeyOut, ValueOut any] = AggregatorFactory[KeyOut, ValueOut, ValueOut]
+
+func statelessMapper[KeyIn, ValueIn, KeyOut, ValueOut any](mapper MapperCtx[KeyIn, ValueIn, KeyOut, ValueOut]) MapperFactory[KeyIn, ValueIn, KeyOut, ValueOut] {
+	return func() ThreadMapper[KeyIn, ValueIn, KeyOut, ValueOut] {
+		return mapper
+	}
+}
+
+func statelessAggregator[KeyOut, ValueMid, ValueOut any](aggregator AggregatorCtx[KeyOut, ValueMid, ValueOut]) AggregatorFactory[KeyOut, ValueMid, ValueOut] {
+	return func() ThreadAggregator[KeyOut, ValueMid, ValueOut] {
+		return aggregator
+	}
+}
+
+// Setup does nothing, so that MapperCtx is a ThreadMapper without state.
+func (mapper MapperCtx[KeyIn, ValueIn, KeyOut, ValueOut]) Setup(*TaskContext) error {
+	return nil
+}
+
+// Map calls the mapper.
+func (mapper MapperCtx[KeyIn, ValueIn, KeyOut, ValueOut]) Map(ctx *TaskContext, key KeyIn, value ValueIn, emit Emitter[KeyOut, ValueOut]) {
+	mapper(ctx, key, value, emit)
+}
+
+// Cleanup does nothing, so that MapperCtx is a ThreadMapper without state.
+func (mapper MapperCtx[KeyIn, ValueIn, KeyOut, ValueOut]) Cleanup(*TaskContext) error {
+	return nil
+}
+
+// Setup does nothing, so that AggregatorCtx is a ThreadAggregator without state.
+func (aggregator AggregatorCtx[KeyOut, ValueMid, ValueOut]) Setup(*TaskContext) error {
+	return nil
+}
+
+// Reduce calls the aggregator.
+func (aggregator AggregatorCtx[KeyOut, ValueMid, ValueOut]) Reduce(ctx *TaskContext, key KeyOut, values []ValueMid) ValueOut {
+	return aggregator(ctx, key, values)
+}
+
+// Cleanup does nothing, so that AggregatorCtx is a ThreadAggregator without state.
+func (aggregator AggregatorCtx[KeyOut, ValueMid, ValueOut]) Cleanup(*TaskContext) error {
+	return nil
+}
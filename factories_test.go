@@ -0,0 +1,139 @@
+package meduce_test
+
+import (
+	"errors"
+	"github.com/djordje200179/meduce"
+	"github.com/djordje200179/meduce/collectors"
+	"github.com/djordje200179/meduce/sources"
+	"sync/atomic"
+	"testing"
+)
+
+type lifecycle struct {
+	setups, cleanups atomic.Int32
+	errSetup         error
+}
+
+type countingMapper struct {
+	lifecycle *lifecycle
+	thread    int
+	ready     bool
+}
+
+func (mapper *countingMapper) Setup(ctx *meduce.TaskContext) error {
+	if err := mapper.lifecycle.errSetup; err != nil {
+		return err
+	}
+
+	mapper.lifecycle.setups.Add(1)
+	mapper.thread = ctx.Thread
+	mapper.ready = true
+	return nil
+}
+
+func (mapper *countingMapper) Map(ctx *meduce.TaskContext, _ int, value int, emit meduce.Emitter[int, int]) {
+	if !mapper.ready || mapper.thread != ctx.Thread {
+		panic("mapper is used without setup or by another thread")
+	}
+
+	emit(value%10, 1)
+}
+
+func (mapper *countingMapper) Cleanup(*meduce.TaskContext) error {
+	mapper.lifecycle.cleanups.Add(1)
+	mapper.ready = false
+	return nil
+}
+
+type countingReducer struct {
+	lifecycle *lifecycle
+	ready     bool
+}
+
+func (reducer *countingReducer) Setup(*meduce.TaskContext) error {
+	reducer.lifecycle.setups.Add(1)
+	reducer.ready = true
+	return nil
+}
+
+func (reducer *countingReducer) Reduce(_ *meduce.TaskContext, key int, values []int) int {
+	if !reducer.ready {
+		panic("reducer is used without setup")
+	}
+
+	return sum(key, values)
+}
+
+func (reducer *countingReducer) Cleanup(*meduce.TaskContext) error {
+	reducer.lifecycle.cleanups.Add(1)
+	reducer.ready = false
+	return nil
+}
+
+func TestFactories(t *testing.T) {
+	var mappers, reducers lifecycle
+
+	collector := collectors.NewMapCollector[int, int]()
+	process := meduce.NewDefaultProcess(meduce.Config[int, int, int, int]{
+		MapperFactory: func() meduce.ThreadMapper[int, int, int, int] {
+			return &countingMapper{lifecycle: &mappers}
+		},
+		ReducerFactory: func() meduce.ThreadReducer[int, int] {
+			return &countingReducer{lifecycle: &reducers}
+		},
+		Source:        sources.NewSliceSource(numbers(1000)),
+		Collector:     collector,
+		MapWorkers:    4,
+		ReduceWorkers: 3,
+	})
+
+	if err := process.Run(); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+
+	for key := range 10 {
+		if collector[key] != 100 {
+			t.Errorf("value of key %d = %d, want 100", key, collector[key])
+		}
+	}
+
+	if setups, cleanups := mappers.setups.Load(), mappers.cleanups.Load(); setups != 4 || cleanups != 4 {
+		t.Errorf("mappers were set up %d and cleaned up %d times, want 4 times", setups, cleanups)
+	}
+
+	// Reducer is used as combiner, so mapping threads get their own instances.
+	if setups, cleanups := reducers.setups.Load(), reducers.cleanups.Load(); setups != 7 || cleanups != 7 {
+		t.Errorf("reducers were set up %d and cleaned up %d times, want 7 times", setups, cleanups)
+	}
+}
+
+func TestFactorySetupError(t *testing.T) {
+	errSetup := errors.New("setup failed")
+	mappers := lifecycle{errSetup: errSetup}
+	var reducers lifecycle
+
+	process := meduce.NewDefaultProcess(meduce.Config[int, int, int, int]{
+		MapperFactory: func() meduce.ThreadMapper[int, int, int, int] {
+			return &countingMapper{lifecycle: &mappers}
+		},
+		ReducerFactory: func() meduce.ThreadReducer[int, int] {
+			return &countingReducer{lifecycle: &reducers}
+		},
+		Source:        sources.NewSliceSource(numbers(1000)),
+		Collector:     collectors.NewMapCollector[int, int](),
+		MapWorkers:    2,
+		ReduceWorkers: 2,
+	})
+
+	if err := process.Run(); !errors.Is(err, errSetup) {
+		t.Errorf("Run() = %v, want %v", err, errSetup)
+	}
+
+	if cleanups := mappers.cleanups.Load(); cleanups != 0 {
+		t.Errorf("mappers were cleaned up %d times, want 0", cleanups)
+	}
+
+	if setups, cleanups := reducers.setups.Load(), reducers.cleanups.Load(); setups != cleanups {
+		t.Errorf("reducers were set up %d and cleaned up %d times, want the same", setups, cleanups)
+	}
+}
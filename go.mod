@@ -1,5 +1,5 @@
 module github.com/djordje200179/meduce
 
-go 1.21
+go 1.24
 
 require github.com/djordje200179/extendedlibrary/misc v1.0.4
@@ -0,0 +1,153 @@
+package meduce
+
+import (
+	"context"
+	"hash/maphash"
+	"slices"
+	"sync"
+)
+
+// A hashGrouper groups emitted key-value pairs by hashing their keys,
+// instead of sorting them with KeyComparator.
+//
+// It is used by processes created with NewHashProcess,
+// as only they know that output keys are comparable.
+type hashGrouper[KeyOut, ValueMid any] interface {
+	newTable(shardsCount int, combiner func(key KeyOut, values []ValueMid) ValueMid) hashTable[KeyOut, ValueMid]
+	mergeShard(tables []hashTable[KeyOut, ValueMid], shard int, yield func(key KeyOut, values []ValueMid) bool)
+}
+
+// A hashTable holds pairs emitted by a single mapping thread,
+// partitioned into shards by hashes of their keys.
+//
+// If the table has a combiner, values are combined as they are added,
+// so that only a single value is kept for each key.
+type hashTable[KeyOut, ValueMid any] interface {
+	add(key KeyOut, value ValueMid)
+	len() int
+	shardsCount() int
+}
+
+type comparableHashGrouper[KeyOut comparable, ValueMid any] struct {
+	seed maphash.Seed
+}
+
+func (grouper comparableHashGrouper[KeyOut, ValueMid]) newTable(
+	shardsCount int,
+	combiner func(key KeyOut, values []ValueMid) ValueMid,
+) hashTable[KeyOut, ValueMid] {
+	table := &comparableHashTable[KeyOut, ValueMid]{
+		seed:     grouper.seed,
+		shards:   make([]map[KeyOut][]ValueMid, shardsCount),
+		combiner: combiner,
+	}
+
+	for i := range table.shards {
+		table.shards[i] = make(map[KeyOut][]ValueMid)
+	}
+
+	return table
+}
+
+func (grouper comparableHashGrouper[KeyOut, ValueMid]) mergeShard(
+	tables []hashTable[KeyOut, ValueMid], shard int,
+	yield func(key KeyOut, values []ValueMid) bool,
+) {
+	merged := make(map[KeyOut][]ValueMid)
+	for _, table := range tables {
+		for key, values := range table.(*comparableHashTable[KeyOut, ValueMid]).shards[shard] {
+			merged[key] = append(merged[key], values...)
+		}
+	}
+
+	for key, values := range merged {
+		if !yield(key, values) {
+			return
+		}
+	}
+}
+
+type comparableHashTable[KeyOut comparable, ValueMid any] struct {
+	seed   maphash.Seed
+	shards []map[KeyOut][]ValueMid
+	count  int
+
+	combiner func(key KeyOut, values []ValueMid) ValueMid
+	combined [2]ValueMid
+}
+
+func (table *comparableHashTable[KeyOut, ValueMid]) add(key KeyOut, value ValueMid) {
+	shard := table.shards[maphash.Comparable(table.seed, key)%uint64(len(table.shards))]
+
+	values, ok := shard[key]
+	if !ok {
+		table.count++
+	} else if table.combiner != nil {
+		table.combined = [2]ValueMid{values[0], value}
+		values[0] = table.combiner(key, table.combined[:])
+		return
+	}
+
+	shard[key] = append(values, value)
+}
+
+func (table *comparableHashTable[KeyOut, ValueMid]) len() int {
+	return table.count
+}
+
+func (table *comparableHashTable[KeyOut, ValueMid]) shardsCount() int {
+	return len(table.shards)
+}
+
+// NewHashProcess creates a new process that groups pairs
+// with comparable keys by hashing them instead of sorting them.
+//
+// Grouping by hashing is faster than sorting, but keys are
+// reduced in no particular order, so KeyComparator is not needed.
+// Mapping threads combine values of each key as soon as they are emitted,
+// so only a single value per key is kept in memory.
+// If ValueComparator is set, values of each key are still sorted,
+// so they are not combined before they are reduced.
+// MemoryLimit is not supported by such processes.
+func NewHashProcess[KeyIn, ValueIn any, KeyOut comparable, ValueMid, ValueOut any](
+	config AggregationConfig[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut],
+) *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut] {
+	process := NewAggregationProcess(config)
+	process.hashGrouper = comparableHashGrouper[KeyOut, ValueMid]{maphash.MakeSeed()}
+
+	return process
+}
+
+func hashDataGenerationThread[KeyOut, ValueMid any](
+	ctx context.Context,
+	grouper hashGrouper[KeyOut, ValueMid],
+	valueComparator func(first, second ValueMid) int,
+	tables []hashTable[KeyOut, ValueMid], shard int,
+	readyDataPool chan<- reducingDataGroup[KeyOut, ValueMid],
+	fail func(err error),
+	finishSignal *sync.WaitGroup,
+) {
+	defer finishSignal.Done()
+
+	state := threadState[any, KeyOut]{index: shard, phase: PhaseMerge}
+	defer state.recoverPanic(fail)
+
+	grouper.mergeShard(tables, shard, func(key KeyOut, values []ValueMid) bool {
+		state.setOutputKey(key)
+		if valueComparator != nil {
+			slices.SortFunc(values, valueComparator)
+		}
+
+		reducerData := reducingDataGroup[KeyOut, ValueMid]{
+			key:    key,
+			values: values,
+		}
+
+		select {
+		case readyDataPool <- reducerData:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package meduce_test
+
+import (
+	"cmp"
+	"github.com/djordje200179/meduce"
+	"github.com/djordje200179/meduce/collectors"
+	"github.com/djordje200179/meduce/sources"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestHashProcess(t *testing.T) {
+	lines := []string{"a b c", "b c", "c", "d d d d"}
+	collector := collectors.NewMapCollector[string, int]()
+
+	process := meduce.NewHashProcess(meduce.Config[int, string, string, int]{
+		Mapper: func(_ int, line string, emit meduce.Emitter[string, int]) {
+			for _, word := range strings.Fields(line) {
+				emit(word, 1)
+			}
+		},
+		Reducer: func(_ string, values []int) int {
+			return sum(0, values)
+		},
+		Source:     sources.NewSliceSource(lines),
+		Collector:  collector,
+		MapWorkers: 2,
+	})
+
+	if err := process.Run(); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+
+	expected := map[string]int{"a": 1, "b": 2, "c": 3, "d": 4}
+	if len(collector) != len(expected) {
+		t.Fatalf("Run() collected %v, want %v", collector, expected)
+	}
+
+	for word, count := range expected {
+		if collector[word] != count {
+			t.Errorf("word %q was counted %d times, want %d", word, collector[word], count)
+		}
+	}
+}
+
+func TestHashProcessCombineOnInsert(t *testing.T) {
+	var valuesMutex sync.Mutex
+	var valuesCounts []int
+
+	collector := collectors.NewMapCollector[int, int]()
+
+	process := meduce.NewHashProcess(meduce.Config[int, int, int, int]{
+		Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+			emit(value%10, 1)
+		},
+		Reducer: func(key int, values []int) int {
+			valuesMutex.Lock()
+			valuesCounts = append(valuesCounts, len(values))
+			valuesMutex.Unlock()
+
+			return sum(key, values)
+		},
+		Source:     sources.NewSliceSource(numbers(1000)),
+		Collector:  collector,
+		MapWorkers: 1,
+	})
+
+	if err := process.Run(); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+
+	for key := range 10 {
+		if collector[key] != 100 {
+			t.Errorf("key %d was counted %d times, want 100", key, collector[key])
+		}
+	}
+
+	// Each emitted value is combined with the single value kept for its key.
+	if len(valuesCounts) != 990 {
+		t.Errorf("values were combined %d times, want 990", len(valuesCounts))
+	}
+
+	for _, count := range valuesCounts {
+		if count != 2 {
+			t.Fatalf("%d values were combined at once, want 2", count)
+		}
+	}
+}
+
+func TestHashProcessValueComparator(t *testing.T) {
+	collector := collectors.NewMapCollector[int, int]()
+
+	process := meduce.NewHashProcess(meduce.Config[int, int, int, int]{
+		Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+			emit(value%10, -value)
+		},
+		// The smallest value is the first one, if values are sorted.
+		Reducer: func(_ int, values []int) int {
+			if !slices.IsSorted(values) {
+				t.Errorf("values %v are not sorted", values)
+			}
+
+			return values[0]
+		},
+		ValueComparator: cmp.Compare[int],
+		Source:          sources.NewSliceSource(numbers(1000)),
+		Collector:       collector,
+		MapWorkers:      2,
+	})
+
+	if err := process.Run(); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+
+	for key := range 10 {
+		if collector[key] != -(990 + key) {
+			t.Errorf("key %d was reduced to %d, want %d", key, collector[key], -(990 + key))
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package meduce
+
+import (
+	"cmp"
+	"context"
+)
+
+// An AggregationJob is a reusable definition of a MapReduce task
+// whose mapped values are reduced to values of a different type.
+//
+// Unlike a process, which can be run only once, a job can be run
+// multiple times, each time with a new source and collector.
+// Every run creates a new process.
+type AggregationJob[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut any] struct {
+	config     AggregationConfig[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]
+	newProcess func(config AggregationConfig[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]
+}
+
+// A Job is a reusable definition of a MapReduce task.
+type Job[KeyIn, ValueIn, KeyOut, ValueOut any] = AggregationJob[KeyIn, ValueIn, KeyOut, ValueOut, ValueOut]
+
+// NewAggregationJob creates a new AggregationJob with given configuration.
+// Source and Collector of the configuration are ignored.
+func NewAggregationJob[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut any](
+	config AggregationConfig[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut],
+) *AggregationJob[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut] {
+	config.Source = Source[KeyIn, ValueIn]{}
+	config.Collector = nil
+
+	return &AggregationJob[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]{
+		config:     config,
+		newProcess: NewAggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut],
+	}
+}
+
+// NewDefaultAggregationJob creates a new AggregationJob
+// with default key comparator for ordered keys.
+func NewDefaultAggregationJob[KeyIn, ValueIn any, KeyOut cmp.Ordered, ValueMid, ValueOut any](
+	config AggregationConfig[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut],
+) *AggregationJob[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut] {
+	config.KeyComparator = cmp.Compare[KeyOut]
+
+	return NewAggregationJob(config)
+}
+
+// NewJob creates a new Job with given configuration.
+// Source and Collector of the configuration are ignored.
+func NewJob[KeyIn, ValueIn, KeyOut, ValueOut any](config Config[KeyIn, ValueIn, KeyOut, ValueOut]) *Job[KeyIn, ValueIn, KeyOut, ValueOut] {
+	return NewAggregationJob(config)
+}
+
+// NewDefaultJob creates a new Job with default key comparator for ordered keys.
+func NewDefaultJob[KeyIn, ValueIn any, KeyOut cmp.Ordered, ValueOut any](
+	config Config[KeyIn, ValueIn, KeyOut, ValueOut],
+) *Job[KeyIn, ValueIn, KeyOut, ValueOut] {
+	return NewDefaultAggregationJob(config)
+}
+
+// NewHashJob creates a new job whose processes group
+// pairs with comparable keys by hashing them.
+func NewHashJob[KeyIn, ValueIn any, KeyOut comparable, ValueMid, ValueOut any](
+	config AggregationConfig[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut],
+) *AggregationJob[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut] {
+	job := NewAggregationJob(config)
+	job.newProcess = NewHashProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]
+
+	return job
+}
+
+// NewProcess creates a new process of the job that reads
+// from the given source and collects into the given collector.
+//
+// The process is not started, so it can be linked
+// with other processes or run asynchronously.
+func (job *AggregationJob[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) NewProcess(
+	source Source[KeyIn, ValueIn],
+	collector Collector[KeyOut, ValueOut],
+) *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut] {
+	config := job.config
+	config.Source = source
+	config.Collector = collector
+
+	return job.newProcess(config)
+}
+
+// Run runs a new process of the job and blocks until it is finished.
+func (job *AggregationJob[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) Run(
+	source Source[KeyIn, ValueIn],
+	collector Collector[KeyOut, ValueOut],
+) error {
+	return job.NewProcess(source, collector).Run()
+}
+
+// RunContext runs a new process of the job and blocks until
+// it is finished or until the given context is cancelled.
+func (job *AggregationJob[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) RunContext(
+	ctx context.Context,
+	source Source[KeyIn, ValueIn],
+	collector Collector[KeyOut, ValueOut],
+) error {
+	return job.NewProcess(source, collector).RunContext(ctx)
+}
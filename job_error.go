@@ -0,0 +1,126 @@
+package meduce
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// A Phase is a stage of the process in which user code is called.
+type Phase int
+
+const (
+	PhaseMap      Phase = iota // PhaseMap is the stage in which Mapper is called
+	PhaseCombine               // PhaseCombine is the stage in which mapped values are combined
+	PhaseReduce                // PhaseReduce is the stage in which Reducer is called
+	PhaseFinalize              // PhaseFinalize is the stage in which Finalizer is called
+	PhaseFilter                // PhaseFilter is the stage in which Filter is called
+	PhaseCollect               // PhaseCollect is the stage in which Collector is called
+	PhaseMerge                 // PhaseMerge is the stage in which mapped data is merged and grouped
+	PhaseObserve               // PhaseObserve is the stage in which Observer is notified
+)
+
+var phaseNames = [...]string{"map", "combine", "reduce", "finalize", "filter", "collect", "merge", "observe"}
+
+func (phase Phase) String() string {
+	if phase < 0 || int(phase) >= len(phaseNames) {
+		return fmt.Sprintf("Phase(%d)", int(phase))
+	}
+
+	return phaseNames[phase]
+}
+
+// A JobError is returned from Run when user code panics
+// inside one of the mapping, merging or reducing threads.
+//
+// The panic is recovered and the whole process is stopped.
+type JobError struct {
+	Phase  Phase // Phase in which the panic happened
+	Thread int   // Thread is the index of the mapping, merging or reducing thread, or -1 outside of them
+
+	// InputKey is the key of the record that was being mapped.
+	// It is nil outside of the map phase.
+	InputKey any
+	// OutputKey is the key of the group that was being processed.
+	// In the map phase, it is the last key emitted for the record,
+	// or nil if nothing was emitted yet.
+	OutputKey any
+
+	Value any    // Value is the value that was passed to panic
+	Stack []byte // Stack is the stack trace of the panicking goroutine
+}
+
+func (err *JobError) Error() string {
+	var key any
+	if err.InputKey != nil {
+		key = err.InputKey
+	} else {
+		key = err.OutputKey
+	}
+
+	return fmt.Sprintf("meduce: panic in %s phase (thread %d, key %v): %v", err.Phase, err.Thread, key, err.Value)
+}
+
+// Unwrap returns the value passed to panic if it is an error.
+func (err *JobError) Unwrap() error {
+	if cause, ok := err.Value.(error); ok {
+		return cause
+	}
+
+	return nil
+}
+
+// threadState tracks what user code a thread is currently running,
+// so that a recovered panic can be reported with its context.
+type threadState[KeyIn, KeyOut any] struct {
+	index int
+	phase Phase
+
+	inputKey     KeyIn
+	hasInputKey  bool
+	outputKey    KeyOut
+	hasOutputKey bool
+}
+
+func (state *threadState[KeyIn, KeyOut]) enter(phase Phase) {
+	state.phase = phase
+	state.hasInputKey = false
+	state.hasOutputKey = false
+}
+
+func (state *threadState[KeyIn, KeyOut]) setInputKey(key KeyIn) {
+	state.inputKey = key
+	state.hasInputKey = true
+	state.hasOutputKey = false
+}
+
+func (state *threadState[KeyIn, KeyOut]) setOutputKey(key KeyOut) {
+	state.outputKey = key
+	state.hasOutputKey = true
+}
+
+func (state *threadState[KeyIn, KeyOut]) jobError(value any) *JobError {
+	err := &JobError{
+		Phase:  state.phase,
+		Thread: state.index,
+		Value:  value,
+		Stack:  debug.Stack(),
+	}
+
+	if state.hasInputKey {
+		err.InputKey = state.inputKey
+	}
+
+	if state.hasOutputKey {
+		err.OutputKey = state.outputKey
+	}
+
+	return err
+}
+
+// recoverPanic reports a panic in user code as a JobError.
+// It is used by threads that are not owned by the process.
+func (state *threadState[KeyIn, KeyOut]) recoverPanic(fail func(err error)) {
+	if value := recover(); value != nil {
+		fail(state.jobError(value))
+	}
+}
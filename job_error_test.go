@@ -0,0 +1,161 @@
+package meduce_test
+
+import (
+	"errors"
+	"github.com/djordje200179/meduce"
+	"github.com/djordje200179/meduce/collectors"
+	"github.com/djordje200179/meduce/sources"
+	"sync"
+	"testing"
+)
+
+// runJobError runs the process and returns the JobError that stopped it.
+func runJobError(t *testing.T, config meduce.Config[int, int, int, int]) *meduce.JobError {
+	t.Helper()
+
+	err := meduce.NewDefaultProcess(config).Run()
+
+	var jobErr *meduce.JobError
+	if !errors.As(err, &jobErr) {
+		t.Fatalf("Run() = %v, want a *meduce.JobError", err)
+	}
+
+	return jobErr
+}
+
+func TestMapperPanic(t *testing.T) {
+	errMapper := errors.New("mapper failed")
+
+	jobErr := runJobError(t, meduce.Config[int, int, int, int]{
+		Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+			emit(value, 1)
+
+			if value == 42 {
+				panic(errMapper)
+			}
+		},
+		Reducer:   sum,
+		Source:    sources.NewSliceSource(numbers(100)),
+		Collector: collectors.NewMapCollector[int, int](),
+	})
+
+	if jobErr.Phase != meduce.PhaseMap {
+		t.Errorf("Phase = %v, want %v", jobErr.Phase, meduce.PhaseMap)
+	}
+
+	if jobErr.InputKey != 42 || jobErr.OutputKey != 42 {
+		t.Errorf("keys = %v, %v, want 42, 42", jobErr.InputKey, jobErr.OutputKey)
+	}
+
+	if !errors.Is(jobErr, errMapper) {
+		t.Errorf("JobError does not wrap the panic value %v", errMapper)
+	}
+
+	if len(jobErr.Stack) == 0 {
+		t.Error("JobError has no stack trace")
+	}
+}
+
+func TestReducerPanic(t *testing.T) {
+	jobErr := runJobError(t, meduce.Config[int, int, int, int]{
+		Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+			emit(value%10, 1)
+		},
+		Reducer: func(key int, values []int) int {
+			if key == 7 && len(values) > 1 {
+				panic("reducer failed")
+			}
+
+			return sum(key, values)
+		},
+		Source:    sources.NewSliceSource(numbers(1000)),
+		Collector: collectors.NewMapCollector[int, int](),
+	})
+
+	if jobErr.Phase != meduce.PhaseCombine && jobErr.Phase != meduce.PhaseReduce {
+		t.Errorf("Phase = %v, want %v or %v", jobErr.Phase, meduce.PhaseCombine, meduce.PhaseReduce)
+	}
+
+	if jobErr.OutputKey != 7 || jobErr.InputKey != nil {
+		t.Errorf("keys = %v, %v, want <nil>, 7", jobErr.InputKey, jobErr.OutputKey)
+	}
+
+	if jobErr.Value != "reducer failed" {
+		t.Errorf("Value = %v, want %q", jobErr.Value, "reducer failed")
+	}
+}
+
+func TestComparatorPanic(t *testing.T) {
+	// Each of two mapping threads maps one record,
+	// so keys are first compared when data is merged.
+	var started sync.WaitGroup
+	started.Add(2)
+
+	process := meduce.NewProcess(meduce.Config[int, int, int, int]{
+		Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+			started.Done()
+			started.Wait()
+
+			emit(5, value)
+		},
+		Reducer: sum,
+		KeyComparator: func(first, second int) int {
+			panic("comparator failed")
+		},
+		Source:     sources.NewSliceSource(numbers(2)),
+		Collector:  collectors.NewMapCollector[int, int](),
+		MapWorkers: 2,
+	})
+
+	err := process.Run()
+
+	var jobErr *meduce.JobError
+	if !errors.As(err, &jobErr) {
+		t.Fatalf("Run() = %v, want a *meduce.JobError", err)
+	}
+
+	if jobErr.Phase != meduce.PhaseMerge {
+		t.Errorf("Phase = %v, want %v", jobErr.Phase, meduce.PhaseMerge)
+	}
+}
+
+type panickingCollector struct {
+	collectors.MapCollector[int, int]
+}
+
+func (panickingCollector) Init() error {
+	panic("collector failed")
+}
+
+func TestLinkedCollectorPanic(t *testing.T) {
+	first := meduce.NewDefaultProcess(meduce.Config[int, int, int, int]{
+		Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+			emit(value%10, 1)
+		},
+		Reducer: sum,
+		Source:  sources.NewSliceSource(numbers(1000)),
+	})
+
+	second := meduce.NewDefaultProcess(meduce.Config[int, int, int, int]{
+		Mapper: func(key int, value int, emit meduce.Emitter[int, int]) {
+			emit(key, value)
+		},
+		Reducer:   sum,
+		Collector: panickingCollector{collectors.NewMapCollector[int, int]()},
+	})
+
+	meduce.Link(first, second)
+
+	first.Run()
+
+	_, err := second.WaitToFinish()
+
+	var jobErr *meduce.JobError
+	if !errors.As(err, &jobErr) || jobErr.Phase != meduce.PhaseCollect {
+		t.Fatalf("WaitToFinish() = %v, want a *meduce.JobError in the collect phase", err)
+	}
+
+	if first.Err() != err {
+		t.Errorf("first.Err() = %v, want %v", first.Err(), err)
+	}
+}
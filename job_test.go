@@ -0,0 +1,71 @@
+package meduce_test
+
+import (
+	"github.com/djordje200179/meduce"
+	"github.com/djordje200179/meduce/collectors"
+	"github.com/djordje200179/meduce/sources"
+	"testing"
+)
+
+func TestJob(t *testing.T) {
+	config := meduce.Config[int, int, int, int]{
+		Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+			emit(value%10, 1)
+		},
+		Reducer: sum,
+		// Source and Collector of the job are ignored.
+		Collector: collectors.NewMapCollector[int, int](),
+	}
+
+	jobs := map[string]*meduce.Job[int, int, int, int]{
+		"sorting": meduce.NewDefaultJob(config),
+		"hashing": meduce.NewHashJob(config),
+	}
+
+	for name, job := range jobs {
+		t.Run(name, func(t *testing.T) {
+			for _, count := range []int{100, 1000} {
+				collector := collectors.NewMapCollector[int, int]()
+
+				if err := job.Run(sources.NewSliceSource(numbers(count)), collector); err != nil {
+					t.Fatalf("Run() = %v", err)
+				}
+
+				for key := range 10 {
+					if collector[key] != count/10 {
+						t.Errorf("key %d was counted %d times in a run over %d records, want %d",
+							key, collector[key], count, count/10)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRunTwice(t *testing.T) {
+	collector := collectors.NewMapCollector[int, int]()
+
+	process := meduce.NewDefaultProcess(meduce.Config[int, int, int, int]{
+		Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+			emit(value%10, 1)
+		},
+		Reducer:   sum,
+		Source:    sources.NewSliceSource(numbers(100)),
+		Collector: collector,
+	})
+
+	if err := process.Run(); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+
+	if err := process.Run(); err != meduce.ErrAlreadyRun {
+		t.Errorf("second Run() = %v, want %v", err, meduce.ErrAlreadyRun)
+	}
+
+	if err := process.Err(); err != nil {
+		t.Errorf("Err() = %v after the second run, want nil", err)
+	}
+	if collector[0] != 10 {
+		t.Errorf("key 0 was counted %d times, want 10", collector[0])
+	}
+}
@@ -0,0 +1,217 @@
+package meduce
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"github.com/djordje200179/extendedlibrary/misc"
+	"github.com/djordje200179/extendedlibrary/misc/functions/comparison"
+	"sync"
+)
+
+// A JoinKind decides which keys are passed to JoinReducer.
+type JoinKind int
+
+const (
+	InnerJoin     JoinKind = iota // InnerJoin keeps keys that have values on both sides
+	LeftOuterJoin                 // LeftOuterJoin keeps keys that have values on the left side
+	FullOuterJoin                 // FullOuterJoin keeps all keys
+)
+
+var joinKindNames = [...]string{"inner", "left outer", "full outer"}
+
+func (kind JoinKind) String() string {
+	if kind < 0 || int(kind) >= len(joinKindNames) {
+		return fmt.Sprintf("JoinKind(%d)", int(kind))
+	}
+
+	return joinKindNames[kind]
+}
+
+// A JoinSide is the side of a join from which a record was read.
+type JoinSide int
+
+const (
+	JoinLeft  JoinSide = iota // JoinLeft is the side of LeftSource
+	JoinRight                 // JoinRight is the side of RightSource
+)
+
+// A JoinInput is a record read by a join process
+// from one of its sources.
+type JoinInput[KeyLeft, ValueLeft, KeyRight, ValueRight any] struct {
+	left  misc.Pair[KeyLeft, ValueLeft]
+	right misc.Pair[KeyRight, ValueRight]
+}
+
+// A JoinValue is a mapped value tagged with the side of the join it came from.
+//
+// Its fields are exported so that it can be encoded with GobCodec.
+type JoinValue[Left, Right any] struct {
+	Left    Left
+	Right   Right
+	IsRight bool
+}
+
+// JoinReducer is a function that reduces values of a single key
+// from both sides of a join into a single value.
+type JoinReducer[KeyOut, Left, Right, ValueOut any] func(key KeyOut, left []Left, right []Right) ValueOut
+
+// A JoinConfig is a configuration for a reduce-side join of two sources.
+//
+// Records of each source are mapped with their own mapper to the same
+// key type. Values of each key are then sorted and grouped as in any other
+// process, and passed to Reducer separated by the side they came from.
+type JoinConfig[KeyLeft, ValueLeft, KeyRight, ValueRight, KeyOut, Left, Right, ValueOut any] struct {
+	Kind          JoinKind
+	KeyComparator comparison.Comparator[KeyOut]
+
+	LeftSource  Source[KeyLeft, ValueLeft]
+	LeftMapper  Mapper[KeyLeft, ValueLeft, KeyOut, Left]
+	RightSource Source[KeyRight, ValueRight]
+	RightMapper Mapper[KeyRight, ValueRight, KeyOut, Right]
+
+	Reducer   JoinReducer[KeyOut, Left, Right, ValueOut]
+	Collector Collector[KeyOut, ValueOut]
+}
+
+// A JoinProcess is a process that joins two sources.
+type JoinProcess[KeyLeft, ValueLeft, KeyRight, ValueRight, KeyOut, Left, Right, ValueOut any] = AggregationProcess[
+	JoinSide, JoinInput[KeyLeft, ValueLeft, KeyRight, ValueRight],
+	KeyOut, JoinValue[Left, Right], ValueOut,
+]
+
+// NewJoinProcess creates a new process that joins two sources.
+//
+// Other options of the process (like workers, memory limit or logger)
+// can be set on the returned process before it is run.
+func NewJoinProcess[KeyLeft, ValueLeft, KeyRight, ValueRight, KeyOut, Left, Right, ValueOut any](
+	config JoinConfig[KeyLeft, ValueLeft, KeyRight, ValueRight, KeyOut, Left, Right, ValueOut],
+) *JoinProcess[KeyLeft, ValueLeft, KeyRight, ValueRight, KeyOut, Left, Right, ValueOut] {
+	type Input = JoinInput[KeyLeft, ValueLeft, KeyRight, ValueRight]
+	type Value = JoinValue[Left, Right]
+
+	process := NewAggregationProcess(AggregationConfig[JoinSide, Input, KeyOut, Value, ValueOut]{
+		KeyComparator: config.KeyComparator,
+
+		Mapper: func(side JoinSide, input Input, emit Emitter[KeyOut, Value]) {
+			if side == JoinLeft {
+				config.LeftMapper(input.left.First, input.left.Second, func(key KeyOut, value Left) {
+					emit(key, Value{Left: value})
+				})
+			} else {
+				config.RightMapper(input.right.First, input.right.Second, func(key KeyOut, value Right) {
+					emit(key, Value{Right: value, IsRight: true})
+				})
+			}
+		},
+		Reducer: func(key KeyOut, values []Value) ValueOut {
+			left, right := splitJoinValues(values)
+			return config.Reducer(key, left, right)
+		},
+		DisableCombining: true,
+
+		Source:    mergeJoinSources(config.LeftSource, config.RightSource),
+		Collector: config.Collector,
+	})
+
+	if config.LeftMapper == nil || config.RightMapper == nil {
+		process.Mapper = nil
+	}
+
+	if config.Reducer == nil {
+		process.Reducer = nil
+	}
+
+	process.groupFilter = func(key KeyOut, values []Value) bool {
+		hasLeft, hasRight := false, false
+		for _, value := range values {
+			if value.IsRight {
+				hasRight = true
+			} else {
+				hasLeft = true
+			}
+		}
+
+		switch config.Kind {
+		case InnerJoin:
+			return hasLeft && hasRight
+		case LeftOuterJoin:
+			return hasLeft
+		default:
+			return true
+		}
+	}
+
+	return process
+}
+
+// NewDefaultJoinProcess creates a new process that joins two sources
+// with default key comparator for ordered keys.
+func NewDefaultJoinProcess[KeyLeft, ValueLeft, KeyRight, ValueRight any, KeyOut cmp.Ordered, Left, Right, ValueOut any](
+	config JoinConfig[KeyLeft, ValueLeft, KeyRight, ValueRight, KeyOut, Left, Right, ValueOut],
+) *JoinProcess[KeyLeft, ValueLeft, KeyRight, ValueRight, KeyOut, Left, Right, ValueOut] {
+	config.KeyComparator = cmp.Compare[KeyOut]
+
+	return NewJoinProcess(config)
+}
+
+func splitJoinValues[Left, Right any](values []JoinValue[Left, Right]) ([]Left, []Right) {
+	var left []Left
+	var right []Right
+
+	for _, value := range values {
+		if value.IsRight {
+			right = append(right, value.Right)
+		} else {
+			left = append(left, value.Left)
+		}
+	}
+
+	return left, right
+}
+
+// mergeJoinSources creates a source that reads both sides
+// of a join, tagging each record with its side.
+// Errors of the sides are errors of the created source.
+//
+// It returns an empty source if any of the sources is empty,
+// so that the process reports a missing source.
+func mergeJoinSources[KeyLeft, ValueLeft, KeyRight, ValueRight any](
+	left Source[KeyLeft, ValueLeft],
+	right Source[KeyRight, ValueRight],
+) Source[JoinSide, JoinInput[KeyLeft, ValueLeft, KeyRight, ValueRight]] {
+	type Input = JoinInput[KeyLeft, ValueLeft, KeyRight, ValueRight]
+
+	if left.records == nil || right.records == nil {
+		return Source[JoinSide, Input]{}
+	}
+
+	return newSource(func(writer SourceWriter[JoinSide, Input]) error {
+		var sidesFinished sync.WaitGroup
+		sidesFinished.Add(2)
+
+		go func() {
+			defer sidesFinished.Done()
+
+			if !forwardSource(writer.Context(), left, func(pair misc.Pair[KeyLeft, ValueLeft]) bool {
+				return writer.Write(JoinLeft, Input{left: pair})
+			}) {
+				left.stop()
+			}
+		}()
+
+		go func() {
+			defer sidesFinished.Done()
+
+			if !forwardSource(writer.Context(), right, func(pair misc.Pair[KeyRight, ValueRight]) bool {
+				return writer.Write(JoinRight, Input{right: pair})
+			}) {
+				right.stop()
+			}
+		}()
+
+		sidesFinished.Wait()
+
+		return errors.Join(left.Err(), right.Err())
+	}, []*sourceState{left.state, right.state})
+}
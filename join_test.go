@@ -0,0 +1,115 @@
+package meduce_test
+
+import (
+	"context"
+	"errors"
+	"github.com/djordje200179/meduce"
+	"github.com/djordje200179/meduce/collectors"
+	"github.com/djordje200179/meduce/sources"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newJoinProcess(
+	kind meduce.JoinKind,
+	titles, ratings meduce.Source[int, string],
+	collector meduce.Collector[string, string],
+) *meduce.JoinProcess[int, string, int, string, string, string, string, string] {
+	split := func(line string, emit meduce.Emitter[string, string]) {
+		id, value, _ := strings.Cut(line, ":")
+		emit(id, value)
+	}
+
+	return meduce.NewDefaultJoinProcess(meduce.JoinConfig[int, string, int, string, string, string, string, string]{
+		Kind:        kind,
+		LeftSource:  titles,
+		LeftMapper:  func(_ int, line string, emit meduce.Emitter[string, string]) { split(line, emit) },
+		RightSource: ratings,
+		RightMapper: func(_ int, line string, emit meduce.Emitter[string, string]) { split(line, emit) },
+		Reducer: func(_ string, titles []string, ratings []string) string {
+			return strings.Join(titles, ",") + "|" + strings.Join(ratings, ",")
+		},
+		Collector: collector,
+	})
+}
+
+func TestJoin(t *testing.T) {
+	titles := []string{"1:Alien", "2:Heat", "3:Ran"}
+	ratings := []string{"1:8", "3:9", "4:5"}
+
+	tests := []struct {
+		kind     meduce.JoinKind
+		expected map[string]string
+	}{
+		{meduce.InnerJoin, map[string]string{"1": "Alien|8", "3": "Ran|9"}},
+		{meduce.LeftOuterJoin, map[string]string{"1": "Alien|8", "2": "Heat|", "3": "Ran|9"}},
+		{meduce.FullOuterJoin, map[string]string{"1": "Alien|8", "2": "Heat|", "3": "Ran|9", "4": "|5"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.kind.String(), func(t *testing.T) {
+			collector := collectors.NewMapCollector[string, string]()
+
+			process := newJoinProcess(test.kind, sources.NewSliceSource(titles), sources.NewSliceSource(ratings), collector)
+			if err := process.Run(); err != nil {
+				t.Fatalf("Run() = %v", err)
+			}
+
+			if len(collector) != len(test.expected) {
+				t.Errorf("collected %v, want %v", collector, test.expected)
+			}
+			for key, value := range test.expected {
+				if collector[key] != value {
+					t.Errorf("key %s was joined to %q, want %q", key, collector[key], value)
+				}
+			}
+
+			// Groups dropped by the join are not filtered out by Filter.
+			stats := process.Stats()
+			if stats.Reductions != len(test.expected) || stats.FilteredOut != 0 {
+				t.Errorf("Reductions = %d and FilteredOut = %d, want %d and 0",
+					stats.Reductions, stats.FilteredOut, len(test.expected))
+			}
+		})
+	}
+}
+
+func TestJoinSourceError(t *testing.T) {
+	errRead := errors.New("read failed")
+	ratings := meduce.NewSource(func(writer meduce.SourceWriter[int, string]) error {
+		writer.Write(0, "1:8")
+		return errRead
+	})
+
+	process := newJoinProcess(meduce.InnerJoin, sources.NewSliceSource([]string{"1:Alien"}), ratings,
+		collectors.NewMapCollector[string, string]())
+
+	if err := process.Run(); !errors.Is(err, errRead) {
+		t.Errorf("Run() = %v, want %v", err, errRead)
+	}
+}
+
+func TestJoinCancel(t *testing.T) {
+	produced := make(chan struct{})
+	titles := meduce.NewSource(func(writer meduce.SourceWriter[int, string]) error {
+		defer close(produced)
+
+		for i := 0; writer.Write(i, "1:title"); i++ {
+		}
+
+		return nil
+	})
+
+	process := newJoinProcess(meduce.InnerJoin, titles, sources.NewSliceSource([]string{"1:8"}),
+		collectors.NewMapCollector[string, string]())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := process.RunContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("RunContext() = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	waitFor(t, produced, "source was not stopped after the process was cancelled")
+}
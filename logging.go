@@ -0,0 +1,122 @@
+package meduce
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Messages of events that are logged with StructuredLogger.
+// They are stable, so that logs can be parsed by other programs.
+const (
+	LogProcessStarted         = "meduce.process.started"
+	LogProcessStopped         = "meduce.process.stopped"
+	LogProcessFinished        = "meduce.process.finished"
+	LogProcessProgress        = "meduce.process.progress"
+	LogSideInputsLoaded       = "meduce.side_inputs.loaded"
+	LogMappingStarted         = "meduce.mapping.started"
+	LogMappingThreadFinished  = "meduce.mapping.thread_finished"
+	LogMappingFinished        = "meduce.mapping.finished"
+	LogReducingStarted        = "meduce.reducing.started"
+	LogReducingThreadFinished = "meduce.reducing.thread_finished"
+	LogReducingFinished       = "meduce.reducing.finished"
+	LogCheckpointSaved        = "meduce.checkpoint.saved"
+	LogCheckpointResumed      = "meduce.checkpoint.resumed"
+)
+
+// LogLevels are levels at which events are logged with StructuredLogger.
+//
+// If a level is nil, Process events are logged at slog.LevelInfo,
+// Thread events at slog.LevelDebug and Stop events at slog.LevelError.
+type LogLevels struct {
+	Process slog.Leveler // Process is the level of events of the whole process and its phases
+	Thread  slog.Leveler // Thread is the level of events of single mapping and reducing threads
+	Stop    slog.Leveler // Stop is the level of the event logged when the process is stopped by an error
+}
+
+func (levels LogLevels) process() slog.Level {
+	if levels.Process == nil {
+		return slog.LevelInfo
+	}
+
+	return levels.Process.Level()
+}
+
+func (levels LogLevels) thread() slog.Level {
+	if levels.Thread == nil {
+		return slog.LevelDebug
+	}
+
+	return levels.Thread.Level()
+}
+
+func (levels LogLevels) stop() slog.Level {
+	if levels.Stop == nil {
+		return slog.LevelError
+	}
+
+	return levels.Stop.Level()
+}
+
+// logEvent logs the event with StructuredLogger, if it is set.
+// Every event has the uid and the name of the process as attributes.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) logEvent(level slog.Level, message string, attrs ...slog.Attr) {
+	logger := process.StructuredLogger
+	if logger == nil || !logger.Enabled(context.Background(), level) {
+		return
+	}
+
+	attrs = append([]slog.Attr{slog.Int("uid", process.uid), slog.String("name", process.Name)}, attrs...)
+	logger.LogAttrs(context.Background(), level, message, attrs...)
+}
+
+func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) logFinished() {
+	thread.logEvent(
+		thread.LogLevels.thread(), LogMappingThreadFinished,
+		slog.String("phase", PhaseMap.String()),
+		slog.Int("thread", thread.index),
+		slog.Int("mappings", thread.mappingsCount),
+		slog.Int64("bytes_read", thread.bytesRead),
+		slog.Int("emits", thread.emitsCount),
+		slog.Int("combinations", thread.combinationsCount),
+		slog.Int("spilled_runs", len(thread.runs)),
+		slog.Int("peak_buffered_pairs", thread.peakBufferedPairs),
+		slog.Duration("map_duration", thread.mapDuration),
+		slog.Duration("combine_duration", thread.combineDuration),
+	)
+}
+
+func (thread *reducingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) logFinished() {
+	thread.logEvent(
+		thread.LogLevels.thread(), LogReducingThreadFinished,
+		slog.String("phase", PhaseReduce.String()),
+		slog.Int("thread", thread.index),
+		slog.Int("reductions", thread.reductionsCount),
+		slog.Int("collections", thread.collectionsCount),
+		slog.Int("filtered_out", thread.filteredCount),
+		slog.Duration("reduce_duration", thread.reduceDuration),
+	)
+}
+
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) logFinished() {
+	stats := &process.stats
+
+	counters := make([]any, 0, len(stats.Counters))
+	for name, value := range stats.Counters {
+		counters = append(counters, slog.Int64(name, value))
+	}
+
+	process.logEvent(
+		process.LogLevels.process(), LogProcessFinished,
+		slog.Int("mappings", stats.Mappings),
+		slog.Int64("bytes_read", stats.BytesRead),
+		slog.Int("emits", stats.Emits),
+		slog.Int("combinations", stats.Combinations),
+		slog.Int("reductions", stats.Reductions),
+		slog.Int("collections", stats.Collections),
+		slog.Int("filtered_out", stats.FilteredOut),
+		slog.Duration("map_duration", stats.MapDuration),
+		slog.Duration("reduce_duration", stats.ReduceDuration),
+		slog.Duration("duration", stats.TotalDuration),
+		slog.Group("counters", counters...),
+	)
+}
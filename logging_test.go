@@ -0,0 +1,125 @@
+package meduce_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/djordje200179/meduce"
+	"github.com/djordje200179/meduce/collectors"
+	"github.com/djordje200179/meduce/sources"
+	"log/slog"
+	"testing"
+)
+
+// logEvents decodes JSON lines written by slog.JSONHandler.
+func logEvents(t *testing.T, output *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var events []map[string]any
+	decoder := json.NewDecoder(output)
+	for decoder.More() {
+		var event map[string]any
+		if err := decoder.Decode(&event); err != nil {
+			t.Fatalf("decoding log: %v", err)
+		}
+		events = append(events, event)
+	}
+
+	return events
+}
+
+func TestStructuredLogger(t *testing.T) {
+	var output bytes.Buffer
+
+	process := meduce.NewDefaultProcess(meduce.Config[int, int, int, int]{
+		Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+			emit(value%10, 1)
+		},
+		Reducer:          sum,
+		Source:           sources.NewSliceSource(numbers(1000)),
+		Collector:        collectors.NewMapCollector[int, int](),
+		MapWorkers:       2,
+		ReduceWorkers:    2,
+		StructuredLogger: slog.New(slog.NewJSONHandler(&output, nil)),
+	})
+
+	if err := process.Run(); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+
+	counts := make(map[string]int)
+	var finished map[string]any
+	for _, event := range logEvents(t, &output) {
+		message := event["msg"].(string)
+		counts[message]++
+
+		if event["level"] != "INFO" {
+			t.Errorf("event %s was logged at %v, want INFO", message, event["level"])
+		}
+		if message == meduce.LogProcessFinished {
+			finished = event
+		}
+	}
+
+	for _, message := range []string{
+		meduce.LogProcessStarted, meduce.LogMappingStarted, meduce.LogMappingFinished,
+		meduce.LogReducingStarted, meduce.LogReducingFinished, meduce.LogProcessFinished,
+	} {
+		if counts[message] != 1 {
+			t.Errorf("event %s was logged %d times, want once", message, counts[message])
+		}
+	}
+
+	// Thread events are logged at Debug level, which is not enabled.
+	if counts[meduce.LogMappingThreadFinished] != 0 || counts[meduce.LogReducingThreadFinished] != 0 {
+		t.Error("thread events were logged below the handler level")
+	}
+
+	if finished["mappings"] != 1000.0 || finished["collections"] != 10.0 {
+		t.Errorf("finished event has mappings %v and collections %v, want 1000 and 10",
+			finished["mappings"], finished["collections"])
+	}
+}
+
+func TestStructuredLoggerLevels(t *testing.T) {
+	var output bytes.Buffer
+
+	process := meduce.NewDefaultProcess(meduce.Config[int, int, int, int]{
+		Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+			emit(value%10, 1)
+		},
+		Reducer: func(key int, values []int) int {
+			panic("reducer failed")
+		},
+		DisableCombining: true,
+		Source:           sources.NewSliceSource(numbers(100)),
+		Collector:        collectors.NewMapCollector[int, int](),
+		MapWorkers:       2,
+		ReduceWorkers:    2,
+		StructuredLogger: slog.New(slog.NewJSONHandler(&output, nil)),
+		LogLevels:        meduce.LogLevels{Thread: slog.LevelInfo, Stop: slog.LevelWarn},
+	})
+
+	if err := process.Run(); err == nil {
+		t.Fatal("Run() succeeded, want an error")
+	}
+
+	counts := make(map[string]int)
+	for _, event := range logEvents(t, &output) {
+		message := event["msg"].(string)
+		counts[message]++
+
+		if message == meduce.LogProcessStopped && event["level"] != "WARN" {
+			t.Errorf("event %s was logged at %v, want WARN", message, event["level"])
+		}
+		if message == meduce.LogMappingThreadFinished && event["phase"] != "map" {
+			t.Errorf("event %s has phase %v, want map", message, event["phase"])
+		}
+	}
+
+	if counts[meduce.LogMappingThreadFinished] != 2 {
+		t.Errorf("event %s was logged %d times, want 2", meduce.LogMappingThreadFinished, counts[meduce.LogMappingThreadFinished])
+	}
+	if counts[meduce.LogProcessStopped] != 1 || counts[meduce.LogProcessFinished] != 0 {
+		t.Error("failed process was not logged as stopped")
+	}
+}
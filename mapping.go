@@ -1,94 +1,112 @@
 package meduce
 
 import (
+	"context"
 	"fmt"
-	"github.com/djordje200179/extendedlibrary/misc/functions/comparison"
-	"runtime"
+	"log/slog"
 	"strings"
 	"sync"
+	"time"
 )
 
-func (process *Process[KeyIn, ValueIn, KeyOut, ValueOut]) mapData() {
-	threadsCount := runtime.NumCPU()
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) mapData(ctx context.Context) {
+	mapStart := time.Now()
+	threadsCount := process.workers(process.MapWorkers)
 
 	var allMappersFinished sync.WaitGroup
 	allMappersFinished.Add(threadsCount)
 
-	process.mappingThreads = make([]mappingThread[KeyIn, ValueIn, KeyOut, ValueOut], threadsCount)
+	recordSize := process.recordSizer()
+	pairSize := process.pairSizer()
+
+	process.mappingThreads = make([]mappingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut], threadsCount)
 
 	for i := range process.mappingThreads {
-		process.mappingThreads[i].Process = process
+		process.mappingThreads[i].AggregationProcess = process
+		process.mappingThreads[i].index = i
+		process.mappingThreads[i].memoryLimit = process.memoryLimit(threadsCount)
+		process.mappingThreads[i].recordSize = recordSize
+		process.mappingThreads[i].pairSize = pairSize
+		if process.hashGrouper != nil {
+			process.mappingThreads[i].table = process.hashGrouper.newTable(
+				process.workers(process.ReduceWorkers),
+				process.mappingThreads[i].tableCombiner(),
+			)
+		}
+		if process.CheckpointDir != "" {
+			process.mappingThreads[i].epochs = make(chan checkpointEpoch[KeyIn, ValueIn], 1)
+		}
+	}
 
-		go process.mappingThreads[i].run(&allMappersFinished)
+	var feedingDone chan struct{}
+	if process.CheckpointDir != "" {
+		// Runs of the resumed checkpoint are merged
+		// together with runs of the first thread.
+		process.mappingThreads[0].runs = process.resumeRuns()
+		process.mappingThreads[0].spilledCount = process.checkpoint.Pairs
+
+		feedingDone = make(chan struct{})
+		go func() {
+			defer close(feedingDone)
+			process.feedEpochs(ctx)
+		}()
+	}
+
+	for i := range process.mappingThreads {
+		go process.mappingThreads[i].run(ctx, &allMappersFinished)
 	}
 
 	if process.Logger != nil {
 		var message string
 		if threadsCount == 1 {
-			message = "Process %d: %d mapping thread was started\n"
+			message = "Process %s: %d mapping thread was started\n"
 		} else {
-			message = "Process %d: %d mapping threads were started\n"
+			message = "Process %s: %d mapping threads were started\n"
 		}
 
-		process.Logger.Printf(message, process.uid, threadsCount)
+		process.Logger.Printf(message, process.label(), threadsCount)
 	}
+	process.logEvent(
+		process.LogLevels.process(), LogMappingStarted,
+		slog.String("phase", PhaseMap.String()),
+		slog.Int("threads", threadsCount),
+	)
 
 	allMappersFinished.Wait()
+	if feedingDone != nil {
+		<-feedingDone
+	}
+	process.recordMappingStats(time.Since(mapStart))
 
-	if process.Logger != nil {
-		process.Logger.Printf("Process %d: all mapping threads finished\n", process.uid)
+	if ctx.Err() != nil {
+		process.releaseMappedData()
+		process.mappingThreads = nil
+		return
 	}
 
-	process.mergeMappedData()
-	for i := range process.mappingThreads {
-		process.mappingThreads[i].keys = nil
-		process.mappingThreads[i].values = nil
+	var pairsCount, runsCount int
+	for _, thread := range process.mappingThreads {
+		pairsCount += thread.combinationsCount
+		runsCount += len(thread.runs)
+		if thread.Len() > 0 {
+			runsCount++
+		}
 	}
 
 	if process.Logger != nil {
 		var sb strings.Builder
 
-		sb.WriteString(fmt.Sprintf("Process %d: mapped data merged\n", process.uid))
-		sb.WriteString(fmt.Sprintf("\t%d key-value pairs left\n", len(process.mappedKeys)))
+		sb.WriteString(fmt.Sprintf("Process %s: all mapping threads finished\n", process.label()))
+		sb.WriteString(fmt.Sprintf("\t%d key-value pairs left\n", pairsCount))
+		sb.WriteString(fmt.Sprintf("\t%d sorted runs to merge\n", runsCount))
 
 		process.Logger.Print(sb.String())
 	}
-}
-
-func (process *Process[KeyIn, ValueIn, KeyOut, ValueOut]) mergeMappedData() {
-	entriesCount := 0
-	for _, thread := range process.mappingThreads {
-		entriesCount += len(thread.keys)
-	}
-
-	process.mappedKeys = make([]KeyOut, entriesCount)
-	process.mappedValues = make([]ValueOut, entriesCount)
-
-	indices := make([]int, len(process.mappingThreads))
-	for i := 0; i < entriesCount; i++ {
-		minIndex := -1
-		var minKey KeyOut
-		var minValue ValueOut
-
-		for j, thread := range process.mappingThreads {
-			if indices[j] >= len(thread.keys) {
-				continue
-			}
-
-			currKey := thread.keys[indices[j]]
-			currValue := thread.values[indices[j]]
-			if minIndex == -1 ||
-				process.KeyComparator(currKey, minKey) == comparison.FirstSmaller ||
-				process.KeyComparator(currKey, minKey) == comparison.Equal && process.ValueComparator != nil && process.ValueComparator(currValue, minValue) == comparison.FirstSmaller {
-				minIndex = j
-				minKey = currKey
-				minValue = currValue
-			}
-		}
-
-		process.mappedKeys[i] = minKey
-		process.mappedValues[i] = minValue
-
-		indices[minIndex]++
-	}
+	process.logEvent(
+		process.LogLevels.process(), LogMappingFinished,
+		slog.String("phase", PhaseMap.String()),
+		slog.Int("pairs", pairsCount),
+		slog.Int("runs", runsCount),
+		slog.Duration("duration", process.stats.MapDuration),
+	)
 }
@@ -1,61 +1,249 @@
 package meduce
 
 import (
+	"context"
 	"fmt"
+	"github.com/djordje200179/extendedlibrary/misc"
 	"github.com/djordje200179/extendedlibrary/misc/functions/comparison"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
-type mappingThread[KeyIn, ValueIn, KeyOut, ValueOut any] struct {
-	*Process[KeyIn, ValueIn, KeyOut, ValueOut]
+type mappingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut any] struct {
+	*AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]
+	threadState[KeyIn, KeyOut]
+	task TaskContext
+
+	mapper   ThreadMapper[KeyIn, ValueIn, KeyOut, ValueMid]
+	combiner ThreadAggregator[KeyOut, ValueMid, ValueMid]
 
 	keys   []KeyOut
-	values []ValueOut
+	values []ValueMid
+
+	table hashTable[KeyOut, ValueMid]
+
+	recordSize   func(key KeyIn, value ValueIn) int64
+	pairSize     func(key KeyOut, value ValueMid) int
+	memoryLimit  int
+	bufferedSize int
+	runs         []string
+	spilledCount int
+
+	epochs        chan checkpointEpoch[KeyIn, ValueIn]
+	compactedRuns []string
 
 	mappingsCount     int
+	bytesRead         int64
 	emitsCount        int
 	combinationsCount int
+	peakBufferedPairs int
+
+	mapDuration     time.Duration
+	combineDuration time.Duration
 }
 
-func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueOut]) run(finishSignal *sync.WaitGroup) {
-	for pair := range thread.Source {
-		thread.Mapper(pair.First, pair.Second, thread.append)
-		thread.mappingsCount++
+func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) run(ctx context.Context, finishSignal *sync.WaitGroup) {
+	defer finishSignal.Done()
+	defer thread.releaseEpochs()
+	defer thread.cleanup()
+	defer thread.recoverPanic()
+
+	thread.task = thread.newTaskContext(ctx, PhaseMap, thread.index)
+	if !thread.setup() {
+		return
 	}
 
-	thread.emitsCount = thread.Len()
+	mapStart := time.Now()
+	var finished bool
+	if thread.epochs != nil {
+		finished = thread.mapEpochs(ctx)
+	} else {
+		finished = thread.mapSource(ctx, thread.Source.records)
+	}
+	thread.mapDuration = time.Since(mapStart) - thread.combineDuration
 
-	sort.Sort(thread)
+	if !finished {
+		return
+	}
 
-	thread.combine()
+	combineStart := time.Now()
+	thread.enter(PhaseCombine)
+	if thread.table != nil {
+		thread.combinationsCount = thread.table.len()
+	} else {
+		thread.sortAndCombine()
+		thread.combinationsCount = thread.spilledCount + thread.Len()
+	}
+	thread.combineDuration += time.Since(combineStart)
 
-	thread.combinationsCount = thread.Len()
+	thread.logFinished()
+	if thread.Observer != nil {
+		thread.enter(PhaseObserve)
+		thread.Observer.OnMapThreadDone(thread.index, thread.stats())
+	}
 
 	if thread.Logger != nil {
 		var sb strings.Builder
 
-		sb.WriteString(fmt.Sprintf("Process %d: mapping thread finished\n", thread.uid))
+		sb.WriteString(fmt.Sprintf("Process %s: mapping thread finished\n", thread.label()))
 		sb.WriteString(fmt.Sprintf("\t%d mappings finished\n", thread.mappingsCount))
 		sb.WriteString(fmt.Sprintf("\t%d emmited key-value pairs\n", thread.emitsCount))
 		sb.WriteString(fmt.Sprintf("\t%d unique keys\n", thread.combinationsCount))
+		sb.WriteString(fmt.Sprintf("\t%d peak buffered key-value pairs\n", thread.peakBufferedPairs))
+		if len(thread.runs) > 0 {
+			sb.WriteString(fmt.Sprintf("\t%d runs spilled to disk\n", len(thread.runs)))
+		}
 
 		thread.Logger.Print(sb.String())
 	}
+}
+
+func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) recoverPanic() {
+	if value := recover(); value != nil {
+		thread.fail(thread.jobError(value))
+	}
+}
+
+func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) mapSource(ctx context.Context, records <-chan misc.Pair[KeyIn, ValueIn]) bool {
+	thread.enter(PhaseMap)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case pair, ok := <-records:
+			if !ok {
+				return true
+			}
+
+			thread.setInputKey(pair.First)
+			thread.mapper.Map(&thread.task, pair.First, pair.Second, thread.append)
+			thread.mappingsCount++
+			thread.measureRecord(pair.First, pair.Second)
+			thread.progress.recordsMapped.Add(1)
+		}
+	}
+}
+
+// measureRecord adds the length of the mapped record to bytes read,
+// unless Source reports bytes read by itself.
+func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) measureRecord(key KeyIn, value ValueIn) {
+	if thread.recordSize == nil {
+		return
+	}
+
+	// Sources report their size or bytes read before they write the first record.
+	if thread.mappingsCount == 1 && thread.Source.state.reportsBytes() {
+		thread.recordSize = nil
+		return
+	}
 
-	finishSignal.Done()
+	size := thread.recordSize(key, value)
+	thread.bytesRead += size
+	thread.progress.bytesRead.Add(size)
 }
 
-func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueOut]) append(key KeyOut, value ValueOut) {
+// mapEpochs maps records of epochs until all of them are fed,
+// and spills data of the thread to CheckpointDir after each of them.
+func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) mapEpochs(ctx context.Context) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case epoch, ok := <-thread.epochs:
+			if !ok {
+				return true
+			}
+
+			if !thread.mapEpoch(ctx, epoch) {
+				return false
+			}
+		}
+	}
+}
+
+func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) mapEpoch(ctx context.Context, epoch checkpointEpoch[KeyIn, ValueIn]) bool {
+	defer epoch.finished.Done()
+
+	if !thread.mapSource(ctx, epoch.records) {
+		return false
+	}
+
+	if thread.Len() == 0 {
+		return true
+	}
+
+	spillStart := time.Now()
+	thread.enter(PhaseCombine)
+	err := thread.spill()
+	if err == nil {
+		err = thread.compactRuns()
+	}
+	thread.combineDuration += time.Since(spillStart)
+
+	if err != nil {
+		thread.fail(err)
+		return false
+	}
+
+	return true
+}
+
+// releaseEpochs marks epochs that the thread didn't map as finished,
+// so that feeding of epochs is not blocked by a thread that stopped.
+func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) releaseEpochs() {
+	if thread.epochs == nil {
+		return
+	}
+
+	for epoch := range thread.epochs {
+		epoch.finished.Done()
+	}
+}
+
+func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) append(key KeyOut, value ValueMid) {
+	thread.setOutputKey(key)
+
+	if thread.table != nil {
+		thread.table.add(key, value)
+		thread.emitsCount++
+		thread.peakBufferedPairs = thread.emitsCount
+		return
+	}
+
 	thread.keys = append(thread.keys, key)
 	thread.values = append(thread.values, value)
+	thread.emitsCount++
+	thread.peakBufferedPairs = max(thread.peakBufferedPairs, thread.Len())
+
+	if thread.memoryLimit == 0 {
+		return
+	}
+
+	thread.bufferedSize += thread.pairSize(key, value)
+	if thread.bufferedSize >= thread.memoryLimit {
+		spillStart := time.Now()
+		thread.phase = PhaseCombine
+		if err := thread.spill(); err != nil {
+			thread.fail(err)
+			thread.memoryLimit = 0
+		}
+		thread.phase = PhaseMap
+		thread.combineDuration += time.Since(spillStart)
+	}
 }
-func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueOut]) Len() int {
+
+func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) sortAndCombine() {
+	sort.Sort(thread)
+	thread.combine()
+}
+func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) Len() int {
 	return len(thread.keys)
 }
 
-func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueOut]) Less(i, j int) bool {
+func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) Less(i, j int) bool {
 	keyComparisonResult := thread.KeyComparator(thread.keys[i], thread.keys[j])
 
 	if keyComparisonResult == comparison.FirstSmaller {
@@ -70,18 +258,20 @@ func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueOut]) Less(i, j int) bo
 	}
 }
 
-func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueOut]) Swap(i, j int) {
+func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) Swap(i, j int) {
 	thread.keys[i], thread.keys[j] = thread.keys[j], thread.keys[i]
 	thread.values[i], thread.values[j] = thread.values[j], thread.values[i]
 }
 
-func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueOut]) combine() {
-	if len(thread.keys) == 0 {
+func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) combine() {
+	if len(thread.keys) == 0 || thread.combiner == nil {
 		return
 	}
 
 	uniqueKeys := make([]KeyOut, 0)
-	combinedValues := make([]ValueOut, 0)
+	combinedValues := make([]ValueMid, 0)
+
+	groupingComparator := thread.groupingComparator()
 
 	lastIndex := -1
 	for i := 1; i <= thread.Len(); i++ {
@@ -90,7 +280,7 @@ func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueOut]) combine() {
 		if i != thread.Len() {
 			currentKey := thread.keys[i]
 
-			if thread.KeyComparator(lastKey, currentKey) == comparison.Equal {
+			if groupingComparator(lastKey, currentKey) == comparison.Equal {
 				continue
 			}
 		}
@@ -98,21 +288,95 @@ func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueOut]) combine() {
 		firstIndex := lastIndex + 1
 		lastIndex = i - 1
 
+		groupKey := thread.keys[firstIndex]
+
 		if firstIndex == lastIndex {
 			value := thread.values[firstIndex]
-			uniqueKeys = append(uniqueKeys, lastKey)
+			uniqueKeys = append(uniqueKeys, groupKey)
 			combinedValues = append(combinedValues, value)
 
 			continue
 		}
 
 		validValues := thread.values[firstIndex : lastIndex+1]
-		reducedValue := thread.Config.Reducer(lastKey, validValues)
+		reducedValue := thread.combineGroup(groupKey, validValues)
 
-		uniqueKeys = append(uniqueKeys, lastKey)
+		uniqueKeys = append(uniqueKeys, groupKey)
 		combinedValues = append(combinedValues, reducedValue)
 	}
 
 	thread.keys = uniqueKeys
 	thread.values = combinedValues
 }
+
+func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) combineGroup(key KeyOut, values []ValueMid) ValueMid {
+	thread.setOutputKey(key)
+	return thread.combiner.Reduce(&thread.task, key, values)
+}
+
+// setup creates the mapper and the combiner of the thread and sets them up.
+// It reports whether they are ready to be used.
+func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) setup() bool {
+	thread.enter(PhaseMap)
+
+	mapper := thread.newMapper()
+	if err := mapper.Setup(&thread.task); err != nil {
+		thread.fail(err)
+		return false
+	}
+	thread.mapper = mapper
+
+	if thread.newCombiner == nil {
+		return true
+	}
+
+	thread.phase = PhaseCombine
+	combiner := thread.newCombiner()
+	if err := combiner.Setup(&thread.task); err != nil {
+		thread.fail(err)
+		return false
+	}
+	thread.combiner = combiner
+
+	return true
+}
+
+// cleanup cleans up the mapper and the combiner
+// of the thread, if they were set up.
+// It runs after panics of the thread have been recovered,
+// so that they are reported with the phase in which they happened.
+func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) cleanup() {
+	defer thread.recoverPanic()
+
+	if thread.mapper != nil {
+		thread.enter(PhaseMap)
+		if err := thread.mapper.Cleanup(&thread.task); err != nil {
+			thread.fail(err)
+		}
+	}
+
+	if thread.combiner != nil {
+		thread.phase = PhaseCombine
+		if err := thread.combiner.Cleanup(&thread.task); err != nil {
+			thread.fail(err)
+		}
+	}
+}
+
+// tableCombiner returns the function with which the hash table
+// of the thread combines values as they are emitted,
+// or nil if they have to be sorted before they are combined.
+// The combiner itself is created later, when the thread is set up.
+func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) tableCombiner() func(key KeyOut, values []ValueMid) ValueMid {
+	if thread.newCombiner == nil || thread.ValueComparator != nil {
+		return nil
+	}
+
+	return func(key KeyOut, values []ValueMid) ValueMid {
+		thread.phase = PhaseCombine
+		value := thread.combineGroup(key, values)
+		thread.phase = PhaseMap
+
+		return value
+	}
+}
@@ -0,0 +1,129 @@
+package meducetest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/djordje200179/extendedlibrary/misc"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// ErrCollectorContract is wrapped by errors that report
+// a violation of the Collector contract.
+var ErrCollectorContract = errors.New("meducetest: collector contract violated")
+
+type collectorState int
+
+const (
+	collectorCreated collectorState = iota
+	collectorInitialized
+	collectorFinalized
+)
+
+// A RecordingCollector is a collector that records collected
+// key-value pairs and checks that it is used as the Collector
+// contract requires: Init is called once, before any Collect,
+// and Finalize is called once, after Init and all Collect calls.
+//
+// Methods that are called out of order return an error wrapping
+// ErrCollectorContract, which is also recorded and returned by Err.
+// It is safe to use from multiple threads.
+type RecordingCollector[KeyOut, ValueOut any] struct {
+	mutex      sync.Mutex
+	state      collectorState
+	pairs      []misc.Pair[KeyOut, ValueOut]
+	violations []error
+}
+
+// NewRecordingCollector creates a new RecordingCollector.
+func NewRecordingCollector[KeyOut, ValueOut any]() *RecordingCollector[KeyOut, ValueOut] {
+	return &RecordingCollector[KeyOut, ValueOut]{}
+}
+
+func (collector *RecordingCollector[KeyOut, ValueOut]) Init() error {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	switch collector.state {
+	case collectorInitialized:
+		return collector.violate("Init was called twice")
+	case collectorFinalized:
+		return collector.violate("Init was called after Finalize")
+	}
+
+	collector.state = collectorInitialized
+	return nil
+}
+
+func (collector *RecordingCollector[KeyOut, ValueOut]) Collect(key KeyOut, value ValueOut) error {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	switch collector.state {
+	case collectorCreated:
+		return collector.violate("Collect was called before Init")
+	case collectorFinalized:
+		return collector.violate("Collect was called after Finalize")
+	}
+
+	collector.pairs = append(collector.pairs, misc.Pair[KeyOut, ValueOut]{key, value})
+	return nil
+}
+
+func (collector *RecordingCollector[KeyOut, ValueOut]) Finalize() error {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	switch collector.state {
+	case collectorCreated:
+		return collector.violate("Finalize was called before Init")
+	case collectorFinalized:
+		return collector.violate("Finalize was called twice")
+	}
+
+	collector.state = collectorFinalized
+	return nil
+}
+
+func (collector *RecordingCollector[KeyOut, ValueOut]) violate(message string) error {
+	err := fmt.Errorf("%w: %s", ErrCollectorContract, message)
+	collector.violations = append(collector.violations, err)
+
+	return err
+}
+
+// Pairs returns a copy of collected key-value pairs, in order of collection.
+func (collector *RecordingCollector[KeyOut, ValueOut]) Pairs() []misc.Pair[KeyOut, ValueOut] {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	pairs := make([]misc.Pair[KeyOut, ValueOut], len(collector.pairs))
+	copy(pairs, collector.pairs)
+
+	return pairs
+}
+
+// Err returns all recorded violations of the contract, or nil if there were none.
+// It should be called after the process finished, as a collector that
+// was initialized, but not finalized, is reported as well.
+func (collector *RecordingCollector[KeyOut, ValueOut]) Err() error {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	violations := slices.Clip(collector.violations)
+	if collector.state == collectorInitialized {
+		violations = append(violations, fmt.Errorf("%w: Finalize was not called", ErrCollectorContract))
+	}
+
+	return errors.Join(violations...)
+}
+
+// Check reports an error to t if the contract was violated.
+func (collector *RecordingCollector[KeyOut, ValueOut]) Check(t testing.TB) {
+	t.Helper()
+
+	if err := collector.Err(); err != nil {
+		t.Error(err)
+	}
+}
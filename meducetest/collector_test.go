@@ -0,0 +1,89 @@
+package meducetest_test
+
+import (
+	"errors"
+	"github.com/djordje200179/extendedlibrary/misc"
+	"github.com/djordje200179/meduce/meducetest"
+	"slices"
+	"testing"
+)
+
+func TestRecordingCollector(t *testing.T) {
+	collector := meducetest.NewRecordingCollector[string, int]()
+
+	if err := collector.Init(); err != nil {
+		t.Fatalf("Init() = %v", err)
+	}
+	if err := collector.Collect("a", 1); err != nil {
+		t.Fatalf("Collect() = %v", err)
+	}
+	if err := collector.Collect("b", 2); err != nil {
+		t.Fatalf("Collect() = %v", err)
+	}
+	if err := collector.Finalize(); err != nil {
+		t.Fatalf("Finalize() = %v", err)
+	}
+
+	collector.Check(t)
+
+	expected := []misc.Pair[string, int]{{"a", 1}, {"b", 2}}
+	if pairs := collector.Pairs(); !slices.Equal(pairs, expected) {
+		t.Errorf("Pairs() = %v, want %v", pairs, expected)
+	}
+}
+
+func TestRecordingCollectorViolations(t *testing.T) {
+	tests := []struct {
+		name string
+		use  func(collector *meducetest.RecordingCollector[string, int]) error
+	}{
+		{"collect before init", func(collector *meducetest.RecordingCollector[string, int]) error {
+			return collector.Collect("a", 1)
+		}},
+		{"finalize before init", func(collector *meducetest.RecordingCollector[string, int]) error {
+			return collector.Finalize()
+		}},
+		{"init twice", func(collector *meducetest.RecordingCollector[string, int]) error {
+			_ = collector.Init()
+			return collector.Init()
+		}},
+		{"finalize twice", func(collector *meducetest.RecordingCollector[string, int]) error {
+			_ = collector.Init()
+			_ = collector.Finalize()
+			return collector.Finalize()
+		}},
+		{"collect after finalize", func(collector *meducetest.RecordingCollector[string, int]) error {
+			_ = collector.Init()
+			_ = collector.Finalize()
+			return collector.Collect("a", 1)
+		}},
+		{"init after finalize", func(collector *meducetest.RecordingCollector[string, int]) error {
+			_ = collector.Init()
+			_ = collector.Finalize()
+			return collector.Init()
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			collector := meducetest.NewRecordingCollector[string, int]()
+
+			if err := test.use(collector); !errors.Is(err, meducetest.ErrCollectorContract) {
+				t.Errorf("error = %v, want %v", err, meducetest.ErrCollectorContract)
+			}
+
+			if err := collector.Err(); !errors.Is(err, meducetest.ErrCollectorContract) {
+				t.Errorf("Err() = %v, want %v", err, meducetest.ErrCollectorContract)
+			}
+		})
+	}
+}
+
+func TestRecordingCollectorNotFinalized(t *testing.T) {
+	collector := meducetest.NewRecordingCollector[string, int]()
+	_ = collector.Init()
+
+	if err := collector.Err(); !errors.Is(err, meducetest.ErrCollectorContract) {
+		t.Errorf("Err() = %v, want %v", err, meducetest.ErrCollectorContract)
+	}
+}
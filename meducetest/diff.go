@@ -0,0 +1,89 @@
+package meducetest
+
+import (
+	"fmt"
+	"github.com/djordje200179/extendedlibrary/misc"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Diff describes differences between expected and actual key-value pairs,
+// one difference per line. It returns an empty string if there are none.
+//
+// Order of pairs is ignored, and keys and values are compared with reflect.DeepEqual.
+// Pairs that have equal keys, but different values, are reported together.
+func Diff[KeyOut, ValueOut any](expected, actual []misc.Pair[KeyOut, ValueOut]) string {
+	missing := removeMatching(expected, actual, func(first, second misc.Pair[KeyOut, ValueOut]) bool {
+		return reflect.DeepEqual(first, second)
+	})
+	unexpected := removeMatching(actual, expected, func(first, second misc.Pair[KeyOut, ValueOut]) bool {
+		return reflect.DeepEqual(first, second)
+	})
+
+	var sb strings.Builder
+
+	for _, expectedPair := range missing {
+		index := -1
+		for i, actualPair := range unexpected {
+			if reflect.DeepEqual(expectedPair.First, actualPair.First) {
+				index = i
+				break
+			}
+		}
+
+		if index == -1 {
+			sb.WriteString(fmt.Sprintf("missing key %v: %v\n", expectedPair.First, expectedPair.Second))
+			continue
+		}
+
+		sb.WriteString(fmt.Sprintf(
+			"key %v: expected %v, got %v\n",
+			expectedPair.First, expectedPair.Second, unexpected[index].Second,
+		))
+		unexpected = append(unexpected[:index], unexpected[index+1:]...)
+	}
+
+	for _, actualPair := range unexpected {
+		sb.WriteString(fmt.Sprintf("unexpected key %v: %v\n", actualPair.First, actualPair.Second))
+	}
+
+	return sb.String()
+}
+
+// removeMatching returns pairs from the first slice that don't have
+// a match in the second one. Every pair of the second slice matches only once.
+func removeMatching[KeyOut, ValueOut any](
+	first, second []misc.Pair[KeyOut, ValueOut],
+	matches func(first, second misc.Pair[KeyOut, ValueOut]) bool,
+) []misc.Pair[KeyOut, ValueOut] {
+	used := make([]bool, len(second))
+	var left []misc.Pair[KeyOut, ValueOut]
+
+	for _, pair := range first {
+		found := false
+		for i := range second {
+			if !used[i] && matches(pair, second[i]) {
+				used[i] = true
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			left = append(left, pair)
+		}
+	}
+
+	return left
+}
+
+// AssertOutput reports an error to t if actual
+// key-value pairs differ from expected ones.
+func AssertOutput[KeyOut, ValueOut any](t testing.TB, expected, actual []misc.Pair[KeyOut, ValueOut]) {
+	t.Helper()
+
+	if diff := Diff(expected, actual); diff != "" {
+		t.Errorf("meducetest: output differs from expected:\n%s", diff)
+	}
+}
@@ -0,0 +1,49 @@
+package meducetest_test
+
+import (
+	"github.com/djordje200179/extendedlibrary/misc"
+	"github.com/djordje200179/meduce/meducetest"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	type Pairs = []misc.Pair[string, int]
+
+	tests := []struct {
+		name     string
+		expected Pairs
+		actual   Pairs
+		diff     string
+	}{
+		{"equal", Pairs{{"a", 1}, {"b", 2}}, Pairs{{"a", 1}, {"b", 2}}, ""},
+		{"reordered", Pairs{{"a", 1}, {"b", 2}}, Pairs{{"b", 2}, {"a", 1}}, ""},
+		{"empty", nil, Pairs{}, ""},
+		{"missing", Pairs{{"a", 1}, {"b", 2}}, Pairs{{"a", 1}}, "missing key b: 2\n"},
+		{"unexpected", Pairs{{"a", 1}}, Pairs{{"a", 1}, {"c", 3}}, "unexpected key c: 3\n"},
+		{"different value", Pairs{{"a", 1}}, Pairs{{"a", 2}}, "key a: expected 1, got 2\n"},
+		{"duplicate", Pairs{{"a", 1}}, Pairs{{"a", 1}, {"a", 1}}, "unexpected key a: 1\n"},
+		{
+			"duplicate keys",
+			Pairs{{"a", 1}, {"a", 2}},
+			Pairs{{"a", 2}, {"a", 3}},
+			"key a: expected 1, got 3\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if diff := meducetest.Diff(test.expected, test.actual); diff != test.diff {
+				t.Errorf("Diff() = %q, want %q", diff, test.diff)
+			}
+		})
+	}
+}
+
+func TestDiffSlices(t *testing.T) {
+	expected := []misc.Pair[string, []int]{{"a", []int{1, 2}}}
+	actual := []misc.Pair[string, []int]{{"a", []int{1, 2}}}
+
+	if diff := meducetest.Diff(expected, actual); diff != "" {
+		t.Errorf("Diff() = %q, want no differences", diff)
+	}
+}
@@ -0,0 +1,111 @@
+// Package meducetest provides utilities for testing MapReduce tasks.
+//
+// Tasks are run synchronously over in-memory input, and their
+// output is returned as a slice that is sorted by keys, so that
+// mappers, combiners and reducers can be tested deterministically.
+package meducetest
+
+import (
+	"github.com/djordje200179/extendedlibrary/misc"
+	"github.com/djordje200179/meduce"
+	"slices"
+	"testing"
+)
+
+// Run runs a process with given configuration over input pairs
+// and blocks until it is finished. Collected pairs are returned
+// sorted by KeyComparator, in order of collection for equal keys.
+//
+// Source and Collector of the configuration are replaced.
+// If MapWorkers or ReduceWorkers are not set, a single thread is used,
+// so that user functions are called in a deterministic order.
+//
+// Returned error is the error that stopped the process,
+// or a violation of the Collector contract by the process.
+//
+// Processes that group keys by hashing them, instead of KeyComparator,
+// are run with RunHash.
+func Run[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut any](
+	config meduce.AggregationConfig[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut],
+	input []misc.Pair[KeyIn, ValueIn],
+) ([]misc.Pair[KeyOut, ValueOut], error) {
+	return run(config, input, meduce.NewAggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut])
+}
+
+// RunHash runs a process like Run, but with keys grouped by hashing them,
+// as in processes created with NewHashProcess. If KeyComparator is not set,
+// collected pairs are returned in order of collection.
+func RunHash[KeyIn, ValueIn any, KeyOut comparable, ValueMid, ValueOut any](
+	config meduce.AggregationConfig[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut],
+	input []misc.Pair[KeyIn, ValueIn],
+) ([]misc.Pair[KeyOut, ValueOut], error) {
+	return run(config, input, meduce.NewHashProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut])
+}
+
+func run[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut any](
+	config meduce.AggregationConfig[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut],
+	input []misc.Pair[KeyIn, ValueIn],
+	newProcess func(config meduce.AggregationConfig[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) *meduce.AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut],
+) ([]misc.Pair[KeyOut, ValueOut], error) {
+	source := make(chan misc.Pair[KeyIn, ValueIn], len(input))
+	for _, pair := range input {
+		source <- pair
+	}
+	close(source)
+
+	collector := NewRecordingCollector[KeyOut, ValueOut]()
+
+	config.Source = meduce.NewChannelSource(source)
+	config.Collector = collector
+	if config.MapWorkers == 0 {
+		config.MapWorkers = 1
+	}
+	if config.ReduceWorkers == 0 {
+		config.ReduceWorkers = 1
+	}
+
+	err := newProcess(config).Run()
+	if err == nil {
+		err = collector.Err()
+	}
+
+	output := collector.Pairs()
+	if config.KeyComparator != nil {
+		slices.SortStableFunc(output, func(first, second misc.Pair[KeyOut, ValueOut]) int {
+			return config.KeyComparator(first.First, second.First)
+		})
+	}
+
+	return output, err
+}
+
+// AssertRun runs a process with given configuration over input pairs
+// and reports an error to t if the process failed
+// or if its output differs from expected pairs.
+func AssertRun[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut any](
+	t testing.TB,
+	config meduce.AggregationConfig[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut],
+	input []misc.Pair[KeyIn, ValueIn],
+	expected []misc.Pair[KeyOut, ValueOut],
+) {
+	t.Helper()
+
+	actual, err := Run(config, input)
+	if err != nil {
+		t.Errorf("meducetest: process failed: %v", err)
+		return
+	}
+
+	AssertOutput(t, expected, actual)
+}
+
+// Lines returns input pairs with given lines as values and
+// their indexes as keys, like the ones that are read by a file source.
+func Lines(lines ...string) []misc.Pair[int, string] {
+	pairs := make([]misc.Pair[int, string], len(lines))
+	for i, line := range lines {
+		pairs[i] = misc.Pair[int, string]{i, line}
+	}
+
+	return pairs
+}
@@ -0,0 +1,90 @@
+package meducetest_test
+
+import (
+	"cmp"
+	"errors"
+	"github.com/djordje200179/extendedlibrary/misc"
+	"github.com/djordje200179/meduce"
+	"github.com/djordje200179/meduce/meducetest"
+	"strings"
+	"testing"
+)
+
+func wordCountConfig() meduce.Config[int, string, string, int] {
+	return meduce.Config[int, string, string, int]{
+		KeyComparator: cmp.Compare[string],
+		Mapper: func(_ int, line string, emit meduce.Emitter[string, int]) {
+			for _, word := range strings.Fields(line) {
+				emit(word, 1)
+			}
+		},
+		Reducer: func(_ string, values []int) int {
+			sum := 0
+			for _, value := range values {
+				sum += value
+			}
+
+			return sum
+		},
+	}
+}
+
+func TestRun(t *testing.T) {
+	output, err := meducetest.Run(wordCountConfig(), meducetest.Lines("b a", "a c", ""))
+	if err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+
+	expected := []misc.Pair[string, int]{{"a", 2}, {"b", 1}, {"c", 1}}
+	for i, pair := range expected {
+		if i >= len(output) || output[i] != pair {
+			t.Fatalf("Run() = %v, want %v in order", output, expected)
+		}
+	}
+}
+
+func TestAssertRun(t *testing.T) {
+	config := wordCountConfig()
+	config.MapWorkers = 4
+	config.ReduceWorkers = 4
+
+	meducetest.AssertRun(t, config, meducetest.Lines("b a", "a c", "c c"), []misc.Pair[string, int]{
+		{"a", 2}, {"b", 1}, {"c", 3},
+	})
+}
+
+func TestRunHash(t *testing.T) {
+	config := wordCountConfig()
+	config.KeyComparator = nil
+
+	if _, err := meducetest.Run(config, meducetest.Lines("a")); !errors.Is(err, meduce.ErrNoKeyComparator) {
+		t.Errorf("Run() = %v, want %v", err, meduce.ErrNoKeyComparator)
+	}
+
+	output, err := meducetest.RunHash(config, meducetest.Lines("b a", "a c"))
+	if err != nil {
+		t.Fatalf("RunHash() = %v", err)
+	}
+
+	meducetest.AssertOutput(t, []misc.Pair[string, int]{{"a", 2}, {"b", 1}, {"c", 1}}, output)
+}
+
+func TestRunPanic(t *testing.T) {
+	config := wordCountConfig()
+	config.Mapper = func(_ int, line string, _ meduce.Emitter[string, int]) {
+		if line == "bad" {
+			panic("bad line")
+		}
+	}
+
+	_, err := meducetest.Run(config, meducetest.Lines("good", "bad"))
+
+	var jobErr *meduce.JobError
+	if !errors.As(err, &jobErr) {
+		t.Fatalf("Run() = %v, want a JobError", err)
+	}
+
+	if jobErr.Phase != meduce.PhaseMap || jobErr.InputKey != 1 || jobErr.Value != "bad line" {
+		t.Errorf("JobError = %+v, want a panic in map phase for key 1", jobErr)
+	}
+}
@@ -0,0 +1,226 @@
+package meduce
+
+import (
+	"container/heap"
+	"context"
+	"github.com/djordje200179/extendedlibrary/misc/functions/comparison"
+	"runtime/debug"
+	"sort"
+)
+
+// A runsMerger merges multiple sorted runs into a single sorted stream.
+//
+// Runs are kept in a heap ordered by their current pairs,
+// so each pair is merged in logarithmic time.
+type runsMerger[KeyOut, ValueMid any] struct {
+	keyComparator   comparison.Comparator[KeyOut]
+	valueComparator comparison.Comparator[ValueMid]
+
+	readers []runReader[KeyOut, ValueMid]
+	active  []runReader[KeyOut, ValueMid]
+}
+
+func newRunsMerger[KeyOut, ValueMid any](
+	keyComparator comparison.Comparator[KeyOut],
+	valueComparator comparison.Comparator[ValueMid],
+	readers []runReader[KeyOut, ValueMid],
+) *runsMerger[KeyOut, ValueMid] {
+	merger := &runsMerger[KeyOut, ValueMid]{
+		keyComparator:   keyComparator,
+		valueComparator: valueComparator,
+
+		readers: readers,
+		active:  make([]runReader[KeyOut, ValueMid], 0, len(readers)),
+	}
+
+	for _, reader := range readers {
+		if reader.next() {
+			merger.active = append(merger.active, reader)
+		}
+	}
+
+	heap.Init(merger)
+
+	return merger
+}
+
+func (merger *runsMerger[KeyOut, ValueMid]) Len() int {
+	return len(merger.active)
+}
+
+func (merger *runsMerger[KeyOut, ValueMid]) Less(i, j int) bool {
+	firstKey, firstValue := merger.active[i].current()
+	secondKey, secondValue := merger.active[j].current()
+
+	keyComparisonResult := merger.keyComparator(firstKey, secondKey)
+
+	if keyComparisonResult == comparison.FirstSmaller {
+		return true
+	} else if keyComparisonResult == comparison.Equal {
+		if merger.valueComparator == nil {
+			return false
+		}
+		return merger.valueComparator(firstValue, secondValue) == comparison.FirstSmaller
+	} else {
+		return false
+	}
+}
+
+func (merger *runsMerger[KeyOut, ValueMid]) Swap(i, j int) {
+	merger.active[i], merger.active[j] = merger.active[j], merger.active[i]
+}
+
+func (merger *runsMerger[KeyOut, ValueMid]) Push(reader any) {
+	merger.active = append(merger.active, reader.(runReader[KeyOut, ValueMid]))
+}
+
+func (merger *runsMerger[KeyOut, ValueMid]) Pop() any {
+	last := merger.active[len(merger.active)-1]
+	merger.active = merger.active[:len(merger.active)-1]
+
+	return last
+}
+
+// next returns the smallest pair of all runs.
+// It returns false when all runs are exhausted or one of them failed.
+func (merger *runsMerger[KeyOut, ValueMid]) next() (KeyOut, ValueMid, bool) {
+	if len(merger.active) == 0 {
+		var key KeyOut
+		var value ValueMid
+		return key, value, false
+	}
+
+	reader := merger.active[0]
+	key, value := reader.current()
+
+	if reader.next() {
+		heap.Fix(merger, 0)
+	} else {
+		heap.Pop(merger)
+
+		if reader.err() != nil {
+			merger.active = nil
+		}
+	}
+
+	return key, value, true
+}
+
+func (merger *runsMerger[KeyOut, ValueMid]) err() error {
+	for _, reader := range merger.readers {
+		if err := reader.err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (merger *runsMerger[KeyOut, ValueMid]) close() {
+	closeRuns(merger.readers)
+}
+
+// createMergers creates mergers for mapped data.
+//
+// Data that is kept in memory is split into at most rangesCount key ranges,
+// so that disjoint ranges can be merged at the same time.
+// Spilled data can't be split, so it is merged by a single merger.
+//
+// Comparators are already called while mergers are created,
+// so their panics are returned as a JobError.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) createMergers(
+	ctx context.Context,
+	rangesCount int,
+) (_ []*runsMerger[KeyOut, ValueMid], err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			err = &JobError{Phase: PhaseMerge, Value: value, Stack: debug.Stack()}
+		}
+	}()
+
+	if process.spilled() {
+		readers, err := process.openRuns(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*runsMerger[KeyOut, ValueMid]{
+			newRunsMerger(process.KeyComparator, process.ValueComparator, readers),
+		}, nil
+	}
+
+	splitKeys := process.splitKeys(rangesCount)
+
+	mergers := make([]*runsMerger[KeyOut, ValueMid], len(splitKeys)+1)
+	for i := range mergers {
+		var readers []runReader[KeyOut, ValueMid]
+
+		for _, thread := range process.mappingThreads {
+			start, end := 0, thread.Len()
+			if i > 0 {
+				start = thread.search(splitKeys[i-1])
+			}
+			if i < len(splitKeys) {
+				end = thread.search(splitKeys[i])
+			}
+
+			if start == end {
+				continue
+			}
+
+			readers = append(readers, &memoryRunReader[KeyOut, ValueMid]{
+				keys:   thread.keys[start:end],
+				values: thread.values[start:end],
+				index:  -1,
+			})
+		}
+
+		mergers[i] = newRunsMerger(process.KeyComparator, process.ValueComparator, readers)
+	}
+
+	return mergers, nil
+}
+
+// splitKeys chooses keys that split mapped data into
+// at most rangesCount ranges of similar sizes.
+// Keys of the same group always end up in the same range.
+//
+// Keys are sampled from the largest run, as it
+// is the best approximation of the key distribution.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) splitKeys(rangesCount int) []KeyOut {
+	var largestRun []KeyOut
+	for _, thread := range process.mappingThreads {
+		if len(thread.keys) > len(largestRun) {
+			largestRun = thread.keys
+		}
+	}
+
+	groupingComparator := process.groupingComparator()
+
+	var splitKeys []KeyOut
+	for i := 1; i < rangesCount; i++ {
+		key := largestRun[len(largestRun)*i/rangesCount]
+
+		if len(splitKeys) > 0 && groupingComparator(splitKeys[len(splitKeys)-1], key) != comparison.FirstSmaller {
+			continue
+		}
+
+		splitKeys = append(splitKeys, key)
+	}
+
+	if len(splitKeys) > 0 && groupingComparator(splitKeys[0], largestRun[0]) != comparison.FirstBigger {
+		splitKeys = splitKeys[1:]
+	}
+
+	return splitKeys
+}
+
+// search returns the index of the first key of the thread
+// whose group is not smaller than the group of the given key.
+func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) search(key KeyOut) int {
+	groupingComparator := thread.groupingComparator()
+
+	return sort.Search(thread.Len(), func(i int) bool {
+		return groupingComparator(thread.keys[i], key) != comparison.FirstSmaller
+	})
+}
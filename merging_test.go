@@ -0,0 +1,66 @@
+package meduce_test
+
+import (
+	"github.com/djordje200179/meduce"
+	"github.com/djordje200179/meduce/collectors"
+	"github.com/djordje200179/meduce/sources"
+	"sync"
+	"testing"
+)
+
+func TestMergeRanges(t *testing.T) {
+	tests := []struct {
+		name        string
+		memoryLimit int
+	}{
+		{name: "in memory"},
+		{name: "spilled", memoryLimit: 5000},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var finalizedMutex sync.Mutex
+			finalized := make(map[int]int)
+
+			collector := collectors.NewMapCollector[int, int]()
+
+			process := meduce.NewDefaultProcess(meduce.Config[int, int, int, int]{
+				Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+					emit(value%1000, 1)
+				},
+				Reducer: sum,
+				Finalizer: func(key int, _ *int) {
+					finalizedMutex.Lock()
+					finalized[key]++
+					finalizedMutex.Unlock()
+				},
+				Source:        sources.NewSliceSource(numbers(50_000)),
+				Collector:     collector,
+				MapWorkers:    4,
+				ReduceWorkers: 8,
+				MemoryLimit:   test.memoryLimit,
+				PairSize:      func(int, int) int { return 1 },
+				SpillDir:      t.TempDir(),
+			})
+
+			if err := process.Run(); err != nil {
+				t.Fatalf("Run() = %v", err)
+			}
+
+			if len(collector) != 1000 {
+				t.Fatalf("Run() collected %d keys, want 1000", len(collector))
+			}
+
+			for key, count := range collector {
+				if count != 50 {
+					t.Errorf("key %d was counted %d times, want 50", key, count)
+				}
+
+				// Each group is merged in a single range, so it is reduced once.
+				if finalized[key] != 1 {
+					t.Errorf("key %d was reduced %d times, want 1", key, finalized[key])
+				}
+			}
+		})
+	}
+}
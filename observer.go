@@ -0,0 +1,52 @@
+package meduce
+
+// An Observer is notified about the progress of a process.
+//
+// Methods are called synchronously from threads of the process,
+// so they should return quickly. OnMapThreadDone, OnReduceGroup and OnCollect
+// can be called from multiple threads at once.
+//
+// If configuration of the process is invalid, only OnStart
+// and OnFinish are called, with the validation error.
+// If a method panics, the process is stopped with a JobError.
+// Embed NopObserver to implement only some of the methods.
+type Observer[KeyOut, ValueOut any] interface {
+	// OnStart is called when the process is started.
+	OnStart()
+	// OnMapThreadDone is called when a mapping thread has mapped
+	// and combined all of its data.
+	OnMapThreadDone(thread int, stats MappingThreadStats)
+	// OnMergeDone is called when all mapped data was grouped
+	// and passed to reducing threads.
+	OnMergeDone()
+	// OnReduceGroup is called before Reducer is called with the group.
+	OnReduceGroup(thread int, key KeyOut, valuesCount int)
+	// OnCollect is called after a pair is passed to Collector or linked process.
+	OnCollect(key KeyOut, value ValueOut)
+	// OnFinish is called when the process is finished,
+	// with its statistics and the error that stopped it.
+	OnFinish(stats Stats, err error)
+}
+
+// A NopObserver is an Observer whose methods do nothing.
+type NopObserver[KeyOut, ValueOut any] struct{}
+
+func (NopObserver[KeyOut, ValueOut]) OnStart()                                              {}
+func (NopObserver[KeyOut, ValueOut]) OnMapThreadDone(thread int, stats MappingThreadStats)  {}
+func (NopObserver[KeyOut, ValueOut]) OnMergeDone()                                          {}
+func (NopObserver[KeyOut, ValueOut]) OnReduceGroup(thread int, key KeyOut, valuesCount int) {}
+func (NopObserver[KeyOut, ValueOut]) OnCollect(key KeyOut, value ValueOut)                  {}
+func (NopObserver[KeyOut, ValueOut]) OnFinish(stats Stats, err error)                       {}
+
+// observe notifies Observer, if it is set, from outside of
+// mapping and reducing threads, and reports its panic as a JobError.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) observe(notify func(observer Observer[KeyOut, ValueOut])) {
+	if process.Observer == nil {
+		return
+	}
+
+	state := threadState[KeyIn, KeyOut]{index: -1, phase: PhaseObserve}
+	defer state.recoverPanic(process.fail)
+
+	notify(process.Observer)
+}
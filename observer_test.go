@@ -0,0 +1,167 @@
+package meduce_test
+
+import (
+	"errors"
+	"github.com/djordje200179/meduce"
+	"github.com/djordje200179/meduce/collectors"
+	"github.com/djordje200179/meduce/sources"
+	"sync"
+	"testing"
+)
+
+// recordingObserver counts calls of each method.
+type recordingObserver struct {
+	mutex sync.Mutex
+
+	calls       map[string]int
+	valuesCount int
+	stats       meduce.Stats
+	err         error
+
+	panicIn string
+}
+
+func newRecordingObserver(panicIn string) *recordingObserver {
+	return &recordingObserver{calls: make(map[string]int), panicIn: panicIn}
+}
+
+func (observer *recordingObserver) record(method string) {
+	observer.mutex.Lock()
+	observer.calls[method]++
+	observer.mutex.Unlock()
+
+	if method == observer.panicIn {
+		panic(method + " failed")
+	}
+}
+
+func (observer *recordingObserver) OnStart() {
+	observer.record("OnStart")
+}
+
+func (observer *recordingObserver) OnMapThreadDone(int, meduce.MappingThreadStats) {
+	observer.record("OnMapThreadDone")
+}
+
+func (observer *recordingObserver) OnMergeDone() {
+	observer.record("OnMergeDone")
+}
+
+func (observer *recordingObserver) OnReduceGroup(_ int, _ int, valuesCount int) {
+	observer.mutex.Lock()
+	observer.valuesCount += valuesCount
+	observer.mutex.Unlock()
+
+	observer.record("OnReduceGroup")
+}
+
+func (observer *recordingObserver) OnCollect(int, int) {
+	observer.record("OnCollect")
+}
+
+func (observer *recordingObserver) OnFinish(stats meduce.Stats, err error) {
+	observer.stats, observer.err = stats, err
+	observer.record("OnFinish")
+}
+
+func observedConfig(observer *recordingObserver) meduce.Config[int, int, int, int] {
+	return meduce.Config[int, int, int, int]{
+		Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+			emit(value%10, 1)
+		},
+		Reducer:          sum,
+		DisableCombining: true,
+		Source:           sources.NewSliceSource(numbers(1000)),
+		Collector:        collectors.NewMapCollector[int, int](),
+		MapWorkers:       3,
+		ReduceWorkers:    2,
+		Observer:         observer,
+	}
+}
+
+func TestObserver(t *testing.T) {
+	observer := newRecordingObserver("")
+
+	if err := meduce.NewDefaultProcess(observedConfig(observer)).Run(); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+
+	want := map[string]int{
+		"OnStart":         1,
+		"OnMapThreadDone": 3,
+		"OnMergeDone":     1,
+		"OnReduceGroup":   10,
+		"OnCollect":       10,
+		"OnFinish":        1,
+	}
+	for method, count := range want {
+		if observer.calls[method] != count {
+			t.Errorf("%s was called %d times, want %d", method, observer.calls[method], count)
+		}
+	}
+
+	if observer.valuesCount != 1000 {
+		t.Errorf("OnReduceGroup got %d values in total, want 1000", observer.valuesCount)
+	}
+	if observer.err != nil || observer.stats.Collections != 10 {
+		t.Errorf("OnFinish got error %v and %d collections, want no error and 10", observer.err, observer.stats.Collections)
+	}
+}
+
+func TestObserverInvalidConfig(t *testing.T) {
+	observer := newRecordingObserver("")
+
+	config := observedConfig(observer)
+	config.Mapper = nil
+
+	if err := meduce.NewDefaultProcess(config).Run(); !errors.Is(err, meduce.ErrNoMapper) {
+		t.Fatalf("Run() = %v, want %v", err, meduce.ErrNoMapper)
+	}
+
+	if observer.calls["OnStart"] != 1 || observer.calls["OnFinish"] != 1 || len(observer.calls) != 2 {
+		t.Errorf("observer was called %v, want only OnStart and OnFinish", observer.calls)
+	}
+	if !errors.Is(observer.err, meduce.ErrNoMapper) {
+		t.Errorf("OnFinish got error %v, want %v", observer.err, meduce.ErrNoMapper)
+	}
+}
+
+func TestObserverPanic(t *testing.T) {
+	for _, method := range []string{"OnStart", "OnMapThreadDone", "OnMergeDone", "OnReduceGroup", "OnFinish"} {
+		t.Run(method, func(t *testing.T) {
+			jobErr := runJobError(t, observedConfig(newRecordingObserver(method)))
+
+			if jobErr.Phase != meduce.PhaseObserve {
+				t.Errorf("Phase = %v, want %v", jobErr.Phase, meduce.PhaseObserve)
+			}
+			if jobErr.Value != method+" failed" {
+				t.Errorf("Value = %v, want %q", jobErr.Value, method+" failed")
+			}
+		})
+	}
+}
+
+func TestLinkedObserverPanic(t *testing.T) {
+	first := meduce.NewDefaultProcess(meduce.Config[int, int, int, int]{
+		Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+			emit(value%10, 1)
+		},
+		Reducer: sum,
+		Source:  sources.NewSliceSource(numbers(1000)),
+	})
+
+	config := observedConfig(newRecordingObserver("OnStart"))
+	config.Source = meduce.Source[int, int]{}
+	second := meduce.NewDefaultProcess(config)
+
+	meduce.Link(first, second)
+
+	first.Run()
+
+	_, err := second.WaitToFinish()
+
+	var jobErr *meduce.JobError
+	if !errors.As(err, &jobErr) || jobErr.Phase != meduce.PhaseObserve || jobErr.Thread != -1 {
+		t.Fatalf("WaitToFinish() = %v, want a *meduce.JobError in the observe phase", err)
+	}
+}
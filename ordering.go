@@ -0,0 +1,129 @@
+package meduce
+
+import (
+	"context"
+	"sync"
+)
+
+type orderedResult[KeyOut, ValueOut any] struct {
+	key   KeyOut
+	value ValueOut
+	keep  bool
+}
+
+// A reorderBuffer receives reduced groups from reducing threads
+// in any order and collects them in the order of their sequence numbers.
+//
+// Groups that arrived too early are kept until all groups before them arrive.
+// If too many of them are kept, reducing threads are blocked until
+// the next group in sequence arrives. That group is always being reduced
+// by some thread, as groups are taken from the pool in sequence.
+type reorderBuffer[KeyOut, ValueOut any] struct {
+	mutex      sync.Mutex
+	groupReady *sync.Cond
+
+	collect func(key KeyOut, value ValueOut) error
+	window  int
+
+	pending map[int]orderedResult[KeyOut, ValueOut]
+	next    int
+
+	// checkpoint is called with the number of collected groups
+	// every checkpointInterval groups, if it is set.
+	checkpoint         func(groupsCount int) error
+	checkpointInterval int
+}
+
+// newReorderBuffer creates a buffer whose first group has the given sequence number.
+// Groups before it were already collected by a process that was resumed.
+func newReorderBuffer[KeyOut, ValueOut any](
+	ctx context.Context,
+	window int,
+	first int,
+	collect func(key KeyOut, value ValueOut) error,
+) *reorderBuffer[KeyOut, ValueOut] {
+	buffer := &reorderBuffer[KeyOut, ValueOut]{
+		collect: collect,
+		window:  window,
+		pending: make(map[int]orderedResult[KeyOut, ValueOut]),
+		next:    first,
+	}
+	buffer.groupReady = sync.NewCond(&buffer.mutex)
+
+	context.AfterFunc(ctx, func() {
+		buffer.mutex.Lock()
+		defer buffer.mutex.Unlock()
+
+		buffer.groupReady.Broadcast()
+	})
+
+	return buffer
+}
+
+// add adds a reduced group to the buffer, and collects all groups
+// that are ready. If keep is false, the group only takes its place
+// in the sequence, but it is not collected.
+func (buffer *reorderBuffer[KeyOut, ValueOut]) add(
+	ctx context.Context,
+	sequence int,
+	key KeyOut, value ValueOut, keep bool,
+) error {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	for len(buffer.pending) >= buffer.window && sequence != buffer.next && ctx.Err() == nil {
+		buffer.groupReady.Wait()
+	}
+
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+
+	buffer.pending[sequence] = orderedResult[KeyOut, ValueOut]{key, value, keep}
+
+	return buffer.flush()
+}
+
+func (buffer *reorderBuffer[KeyOut, ValueOut]) flush() error {
+	defer buffer.groupReady.Broadcast()
+
+	for {
+		result, ok := buffer.pending[buffer.next]
+		if !ok {
+			return nil
+		}
+
+		delete(buffer.pending, buffer.next)
+		buffer.next++
+
+		if result.keep {
+			if err := buffer.collect(result.key, result.value); err != nil {
+				return err
+			}
+		}
+
+		if buffer.checkpoint != nil && buffer.next%buffer.checkpointInterval == 0 {
+			if err := buffer.checkpoint(buffer.next); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// deliver passes a reduced group to the collector,
+// through the reorder buffer if output should be ordered.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) deliver(
+	ctx context.Context,
+	sequence int,
+	key KeyOut, value ValueOut, keep bool,
+) error {
+	if process.reorderBuffer != nil {
+		return process.reorderBuffer.add(ctx, sequence, key, value, keep)
+	}
+
+	if !keep {
+		return nil
+	}
+
+	return process.collect(ctx, key, value)
+}
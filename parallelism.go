@@ -0,0 +1,179 @@
+package meduce
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// A Parallelism is a function that returns how many
+// threads a phase of the process should use.
+//
+// NumCPU, GOMAXPROCS and CPUQuota are predefined parallelisms,
+// and Share can be used to split any of them between multiple processes.
+type Parallelism func() int
+
+// NumCPU returns the number of logical CPUs of the machine.
+func NumCPU() int {
+	return runtime.NumCPU()
+}
+
+// GOMAXPROCS returns the current value of GOMAXPROCS.
+func GOMAXPROCS() int {
+	return runtime.GOMAXPROCS(0)
+}
+
+// CPUQuota returns the number of CPUs that the process is allowed
+// to use by its cgroup CPU quota, but not more than GOMAXPROCS.
+//
+// Quotas of the cgroup of the process and all its ancestors
+// are respected. If no quota is set or it can't be read,
+// GOMAXPROCS is returned.
+func CPUQuota() int {
+	limit := GOMAXPROCS()
+
+	if cpus, ok := cpuQuota("/"); ok && cpus < limit {
+		return cpus
+	}
+
+	return limit
+}
+
+// Share creates a Parallelism that gives one of the given
+// number of equal parts of the parallelism.
+//
+// It can be used to run multiple processes at once
+// without each one of them using all cores.
+// Returned parallelism is always at least 1.
+func Share(parallelism Parallelism, parts int) Parallelism {
+	if parts < 1 {
+		parts = 1
+	}
+
+	return func() int {
+		share := parallelism() / parts
+		if share < 1 {
+			return 1
+		}
+
+		return share
+	}
+}
+
+// cpuQuota returns the number of CPUs allowed by the lowest CPU quota
+// of the cgroup of the process and its ancestors, for both cgroup v1 and v2.
+//
+// Files are read relative to the given root directory.
+func cpuQuota(root string) (cpus int, ok bool) {
+	content, err := os.ReadFile(filepath.Join(root, "proc/self/cgroup"))
+	if err != nil {
+		return 0, false
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+
+		var hierarchy string
+		var readQuota func(dir string) (quota, period int64, ok bool)
+		switch {
+		case fields[0] == "0" && fields[1] == "":
+			hierarchy, readQuota = "sys/fs/cgroup", readCgroupV2Quota
+		case slices.Contains(strings.Split(fields[1], ","), "cpu"):
+			hierarchy, readQuota = "sys/fs/cgroup/cpu", readCgroupV1Quota
+		default:
+			continue
+		}
+
+		// Inside of containers, the hierarchy is often mounted at the
+		// cgroup of the process, so missing directories are skipped.
+		for path := fields[2]; ; path = filepath.Dir(path) {
+			quota, period, found := readQuota(filepath.Join(root, hierarchy, path))
+			if found && quota > 0 && period > 0 {
+				if quotaCPUs := int((quota + period - 1) / period); !ok || quotaCPUs < cpus {
+					cpus, ok = quotaCPUs, true
+				}
+			}
+
+			if path == "/" || path == "." {
+				break
+			}
+		}
+	}
+
+	return cpus, ok
+}
+
+func readCgroupV2Quota(dir string) (quota, period int64, ok bool) {
+	content, err := os.ReadFile(filepath.Join(dir, "cpu.max"))
+	if err != nil {
+		return 0, 0, false
+	}
+
+	fields := strings.Fields(string(content))
+	if len(fields) != 2 || fields[0] == "max" {
+		return 0, 0, false
+	}
+
+	quota, err = strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	period, err = strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return quota, period, true
+}
+
+func readCgroupV1Quota(dir string) (quota, period int64, ok bool) {
+	quota, ok = readCgroupValue(filepath.Join(dir, "cpu.cfs_quota_us"))
+	if !ok {
+		return 0, 0, false
+	}
+
+	period, ok = readCgroupValue(filepath.Join(dir, "cpu.cfs_period_us"))
+	if !ok {
+		return 0, 0, false
+	}
+
+	return quota, period, true
+}
+
+func readCgroupValue(path string) (int64, bool) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+
+	value, err := strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return value, true
+}
+
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) workers(configured int) int {
+	if configured > 0 {
+		return configured
+	}
+
+	parallelism := process.Parallelism
+	if parallelism == nil {
+		parallelism = NumCPU
+	}
+
+	if workers := parallelism(); workers > 0 {
+		return workers
+	}
+
+	return 1
+}
@@ -0,0 +1,107 @@
+package meduce
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFiles creates files with given contents under the root directory.
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+
+	for path, content := range files {
+		path = filepath.Join(root, path)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCPUQuota(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		cpus  int
+		ok    bool
+	}{
+		{
+			name: "v2 nested cgroup",
+			files: map[string]string{
+				"proc/self/cgroup":                   "0::/kubepods/pod\n",
+				"sys/fs/cgroup/cpu.max":              "max 100000\n",
+				"sys/fs/cgroup/kubepods/pod/cpu.max": "250000 100000\n",
+				"sys/fs/cgroup/kubepods/cpu.max":     "max 100000\n",
+				"sys/fs/cgroup/other/pod/cpu.max":    "100000 100000\n",
+			},
+			cpus: 3,
+			ok:   true,
+		},
+		{
+			name: "v2 ancestor quota",
+			files: map[string]string{
+				"proc/self/cgroup":                   "0::/kubepods/pod\n",
+				"sys/fs/cgroup/kubepods/pod/cpu.max": "400000 100000\n",
+				"sys/fs/cgroup/kubepods/cpu.max":     "200000 100000\n",
+			},
+			cpus: 2,
+			ok:   true,
+		},
+		{
+			name: "v2 container namespace",
+			files: map[string]string{
+				"proc/self/cgroup":      "0::/\n",
+				"sys/fs/cgroup/cpu.max": "50000 100000\n",
+			},
+			cpus: 1,
+			ok:   true,
+		},
+		{
+			name: "v2 without quota",
+			files: map[string]string{
+				"proc/self/cgroup":                 "0::/user.slice\n",
+				"sys/fs/cgroup/user.slice/cpu.max": "max 100000\n",
+			},
+		},
+		{
+			name: "v1 mounted at process cgroup",
+			files: map[string]string{
+				"proc/self/cgroup":                      "12:memory:/docker/abc\n4:cpu,cpuacct:/docker/abc\n",
+				"sys/fs/cgroup/cpu/cpu.cfs_quota_us":    "300000\n",
+				"sys/fs/cgroup/cpu/cpu.cfs_period_us":   "100000\n",
+				"sys/fs/cgroup/memory/cpu.cfs_quota_us": "100000\n",
+			},
+			cpus: 3,
+			ok:   true,
+		},
+		{
+			name: "v1 without quota",
+			files: map[string]string{
+				"proc/self/cgroup": "4:cpu,cpuacct:/system.slice\n",
+				"sys/fs/cgroup/cpu/system.slice/cpu.cfs_quota_us":  "-1\n",
+				"sys/fs/cgroup/cpu/system.slice/cpu.cfs_period_us": "100000\n",
+			},
+		},
+		{
+			name:  "no cgroup",
+			files: map[string]string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, test.files)
+
+			cpus, ok := cpuQuota(root)
+			if cpus != test.cpus || ok != test.ok {
+				t.Errorf("cpuQuota() = %d, %t, want %d, %t", cpus, ok, test.cpus, test.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package meduce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/djordje200179/extendedlibrary/misc"
+	"reflect"
+	"sync"
+)
+
+// Errors that are returned when a pipeline can't be built.
+var (
+	ErrPipelineCycle   = errors.New("meduce: pipeline can't contain cycles")
+	ErrPipelineStarted = errors.New("meduce: pipeline was already started")
+	ErrSourceSet       = errors.New("meduce: process that has its own Source can't be connected to")
+)
+
+// A processLink is an output of a process
+// that passes its pairs to another process.
+type processLink[KeyOut, ValueOut any] struct {
+	buffer chan<- misc.Pair[KeyOut, ValueOut]
+	// release is called when the process won't send any more pairs.
+	release func()
+}
+
+// releaseLinks tells all linked processes that
+// the process won't send them any more pairs.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) releaseLinks() {
+	for _, link := range process.links {
+		link.release()
+	}
+}
+
+// A Stage is a process that can be added to a pipeline.
+// All processes are stages.
+type Stage interface {
+	Uid() int
+	RunContext(ctx context.Context) error
+
+	label() string
+	fail(err error)
+	inputType() reflect.Type
+	outputType() reflect.Type
+	hasSource() bool
+	// connectInput returns the channel from which the stage reads
+	// its input in a pipeline, and the function that is called
+	// when one of the stages that write to it is finished.
+	connectInput(bufferSize int) (buffer any, release func())
+	// connectOutput makes the stage send its output to the given
+	// channel, which must be of type returned by outputType.
+	connectOutput(buffer any, release func())
+	// linkFailure makes the stage call the given function when it fails.
+	linkFailure(fail func(err error))
+}
+
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) inputType() reflect.Type {
+	return reflect.TypeFor[misc.Pair[KeyIn, ValueIn]]()
+}
+
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) outputType() reflect.Type {
+	return reflect.TypeFor[misc.Pair[KeyOut, ValueOut]]()
+}
+
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) hasSource() bool {
+	return process.Source.records != nil && process.pipelineInput == nil
+}
+
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) connectInput(bufferSize int) (any, func()) {
+	if process.pipelineInput == nil {
+		process.pipelineInput = make(chan misc.Pair[KeyIn, ValueIn], bufferSize)
+		process.Source = NewChannelSource[KeyIn, ValueIn](process.pipelineInput)
+	}
+
+	process.inputsLeft.Add(1)
+
+	release := func() {
+		if process.inputsLeft.Add(-1) == 0 {
+			close(process.pipelineInput)
+		}
+	}
+
+	return process.pipelineInput, release
+}
+
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) connectOutput(buffer any, release func()) {
+	process.links = append(process.links, processLink[KeyOut, ValueOut]{
+		buffer:  buffer.(chan misc.Pair[KeyOut, ValueOut]),
+		release: release,
+	})
+}
+
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) linkFailure(fail func(err error)) {
+	process.failLinked = append(process.failLinked, fail)
+}
+
+// A Pipeline is a graph of processes, in which output of a process
+// can be passed to multiple processes, and a process can read
+// outputs of multiple processes.
+//
+// All processes of a pipeline are started, waited on
+// and cancelled together. The graph must not contain cycles.
+type Pipeline struct {
+	stages []Stage
+	next   map[Stage][]Stage
+
+	cancel   context.CancelCauseFunc
+	finished sync.WaitGroup
+
+	errMutex sync.Mutex
+	err      error
+}
+
+// NewPipeline creates a new empty pipeline.
+func NewPipeline() *Pipeline {
+	return &Pipeline{
+		next: make(map[Stage][]Stage),
+	}
+}
+
+// Add adds stages to the pipeline before it is started.
+// Stages that are connected are added automatically.
+func (pipeline *Pipeline) Add(stages ...Stage) {
+	for _, stage := range stages {
+		if _, ok := pipeline.next[stage]; ok {
+			continue
+		}
+
+		pipeline.stages = append(pipeline.stages, stage)
+		pipeline.next[stage] = nil
+	}
+}
+
+// Connect passes output of one stage to the input of another.
+//
+// An error is returned if the output type of the first stage
+// is not the same as the input type of the second one.
+func (pipeline *Pipeline) Connect(from, to Stage) error {
+	return pipeline.ConnectWithBufferSize(from, to, 100)
+}
+
+// ConnectWithBufferSize passes output of one stage to the input
+// of another, through a buffer of given size.
+//
+// If multiple stages are connected to the same stage, the size
+// of the buffer is determined by the first connection.
+func (pipeline *Pipeline) ConnectWithBufferSize(from, to Stage, bufferSize int) error {
+	if pipeline.cancel != nil {
+		return ErrPipelineStarted
+	}
+
+	if from.outputType() != to.inputType() {
+		return fmt.Errorf(
+			"meduce: output of process %s (%v) doesn't match input of process %s (%v)",
+			from.label(), from.outputType(), to.label(), to.inputType(),
+		)
+	}
+
+	if to.hasSource() {
+		return ErrSourceSet
+	}
+
+	if pipeline.reaches(to, from) {
+		return ErrPipelineCycle
+	}
+
+	pipeline.Add(from, to)
+	pipeline.next[from] = append(pipeline.next[from], to)
+
+	buffer, release := to.connectInput(bufferSize)
+	from.connectOutput(buffer, release)
+
+	from.linkFailure(to.fail)
+	to.linkFailure(from.fail)
+
+	return nil
+}
+
+// reaches reports whether there is a path between the given stages.
+func (pipeline *Pipeline) reaches(from, to Stage) bool {
+	if from == to {
+		return true
+	}
+
+	for _, next := range pipeline.next[from] {
+		if pipeline.reaches(next, to) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Start starts all stages of the pipeline.
+//
+// Cancellation of the given context stops all of them.
+func (pipeline *Pipeline) Start(ctx context.Context) error {
+	if pipeline.cancel != nil {
+		return ErrPipelineStarted
+	}
+
+	ctx, pipeline.cancel = context.WithCancelCause(ctx)
+
+	pipeline.finished.Add(len(pipeline.stages))
+	for _, stage := range pipeline.stages {
+		go func() {
+			defer pipeline.finished.Done()
+
+			if err := stage.RunContext(ctx); err != nil {
+				pipeline.fail(err)
+			}
+		}()
+	}
+
+	return nil
+}
+
+// Wait blocks until all stages of the pipeline are finished.
+// It returns the first error that stopped one of them.
+func (pipeline *Pipeline) Wait() error {
+	pipeline.finished.Wait()
+
+	pipeline.errMutex.Lock()
+	defer pipeline.errMutex.Unlock()
+
+	return pipeline.err
+}
+
+// Cancel stops all stages of the pipeline.
+func (pipeline *Pipeline) Cancel() {
+	if pipeline.cancel != nil {
+		pipeline.cancel(context.Canceled)
+	}
+}
+
+// Run starts all stages of the pipeline and blocks until they are finished.
+func (pipeline *Pipeline) Run(ctx context.Context) error {
+	if err := pipeline.Start(ctx); err != nil {
+		return err
+	}
+
+	return pipeline.Wait()
+}
+
+// fail records the first error that stopped a stage,
+// and stops all other stages.
+func (pipeline *Pipeline) fail(err error) {
+	pipeline.errMutex.Lock()
+	defer pipeline.errMutex.Unlock()
+
+	if pipeline.err != nil {
+		return
+	}
+
+	pipeline.err = err
+	pipeline.cancel(err)
+
+	for _, stage := range pipeline.stages {
+		stage.fail(err)
+	}
+}
@@ -0,0 +1,111 @@
+package meduce_test
+
+import (
+	"context"
+	"errors"
+	"github.com/djordje200179/meduce"
+	"github.com/djordje200179/meduce/collectors"
+	"github.com/djordje200179/meduce/sources"
+	"testing"
+)
+
+// passingConfig creates a configuration of a process that passes pairs
+// to the next processes, multiplying their values by the factor.
+func passingConfig(factor int) meduce.Config[int, int, int, int] {
+	return meduce.Config[int, int, int, int]{
+		Mapper: func(key int, value int, emit meduce.Emitter[int, int]) {
+			emit(key, value*factor)
+		},
+		Reducer: sum,
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	counting := meduce.NewDefaultProcess(meduce.Config[int, int, int, int]{
+		Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+			emit(value%10, 1)
+		},
+		Reducer: sum,
+		Source:  sources.NewSliceSource(numbers(1000)),
+	})
+	doubling := meduce.NewDefaultProcess(passingConfig(2))
+	tripling := meduce.NewDefaultProcess(passingConfig(3))
+
+	collector := collectors.NewMapCollector[int, int]()
+	summingConfig := passingConfig(1)
+	summingConfig.Collector = collector
+	summing := meduce.NewDefaultProcess(summingConfig)
+
+	pipeline := meduce.NewPipeline()
+	for _, connection := range [][2]meduce.Stage{
+		{counting, doubling},
+		{counting, tripling},
+		{doubling, summing},
+		{tripling, summing},
+	} {
+		if err := pipeline.Connect(connection[0], connection[1]); err != nil {
+			t.Fatalf("Connect() = %v", err)
+		}
+	}
+
+	if err := pipeline.Connect(summing, counting); !errors.Is(err, meduce.ErrSourceSet) {
+		t.Errorf("Connect() to a process with Source = %v, want %v", err, meduce.ErrSourceSet)
+	}
+	if err := pipeline.Connect(summing, doubling); !errors.Is(err, meduce.ErrPipelineCycle) {
+		t.Errorf("Connect() that makes a cycle = %v, want %v", err, meduce.ErrPipelineCycle)
+	}
+
+	mismatched := meduce.NewDefaultProcess(meduce.Config[string, int, string, int]{
+		Mapper: func(key string, value int, emit meduce.Emitter[string, int]) {
+			emit(key, value)
+		},
+		Reducer: func(_ string, values []int) int {
+			return sum(0, values)
+		},
+	})
+	if err := pipeline.Connect(summing, mismatched); err == nil {
+		t.Error("Connect() of mismatched types succeeded")
+	}
+
+	if err := pipeline.Run(context.Background()); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+
+	// Each key is counted 100 times, and then doubled and tripled.
+	for key := range 10 {
+		if collector[key] != 500 {
+			t.Errorf("key %d was summed to %d, want 500", key, collector[key])
+		}
+	}
+
+	if err := pipeline.Start(context.Background()); !errors.Is(err, meduce.ErrPipelineStarted) {
+		t.Errorf("second Start() = %v, want %v", err, meduce.ErrPipelineStarted)
+	}
+}
+
+func TestPipelineFailure(t *testing.T) {
+	first := meduce.NewDefaultProcess(meduce.Config[int, int, int, int]{
+		Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+			emit(value%10, 1)
+		},
+		Reducer: sum,
+		Source:  sources.NewSliceSource(numbers(1000)),
+	})
+
+	failingConfig := passingConfig(1)
+	failingConfig.Collector = &failingCollector{MapCollector: collectors.NewMapCollector[int, int](), key: 5}
+	failing := meduce.NewDefaultProcess(failingConfig)
+
+	pipeline := meduce.NewPipeline()
+	if err := pipeline.Connect(first, failing); err != nil {
+		t.Fatalf("Connect() = %v", err)
+	}
+
+	if err := pipeline.Run(context.Background()); !errors.Is(err, errCollect) {
+		t.Fatalf("Run() = %v, want %v", err, errCollect)
+	}
+
+	if !errors.Is(first.Err(), errCollect) {
+		t.Errorf("first process was stopped with %v, want %v", first.Err(), errCollect)
+	}
+}
@@ -4,64 +4,218 @@ package meduce
 
 import (
 	"cmp"
+	"context"
 	"github.com/djordje200179/extendedlibrary/misc"
 	"github.com/djordje200179/extendedlibrary/misc/functions/comparison"
 	"log"
+	"log/slog"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
-// A Config is a configuration for a single MapReduce task.
-type Config[KeyIn, ValueIn, KeyOut, ValueOut any] struct {
+// An AggregationConfig is a configuration for a single MapReduce task
+// whose mapped values (of type ValueMid) are reduced to values of a different type.
+//
+// If ValueMid and ValueOut are the same type, it is the same as Config:
+// Reducer is also used as combiner, and it is not called for keys with a single value.
+type AggregationConfig[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut any] struct {
+	// Name is shown in logs and in the registry of processes,
+	// to tell processes apart. It doesn't have to be unique.
+	Name string
+
 	// KeyComparator and ValueComparator are used to sort key-value pairs
 	// before they are passed to the Reducer.
 	// KeyComparator is used as primary comparator,
 	// and ValueComparator is used as secondary.
 	KeyComparator   comparison.Comparator[KeyOut]
-	ValueComparator comparison.Comparator[ValueOut]
-
-	Mapper    Mapper[KeyIn, ValueIn, KeyOut, ValueOut]
-	Reducer   Reducer[KeyOut, ValueOut]
-	Finalizer Finalizer[KeyOut, ValueOut]
-	Filter    Filter[KeyOut, ValueOut]
+	ValueComparator comparison.Comparator[ValueMid]
+	// GroupingComparator decides which sorted keys are passed
+	// to a single Combiner and Reducer call. It must be consistent
+	// with KeyComparator, so that keys of a group are next to each other.
+	// Functions are called with the first key of the group.
+	//
+	// It is used for secondary sort, where keys are sorted on their
+	// full value, but are grouped only by a part of it.
+	// If it is nil, KeyComparator is used.
+	// It is not used by processes that group keys by hashing.
+	GroupingComparator comparison.Comparator[KeyOut]
+
+	// OrderedOutput makes the process collect key-value pairs
+	// in the order of their keys. Reducer is still called
+	// from multiple threads at once, but reduced values wait
+	// in a buffer until all values before them are collected.
+	// It is not supported by processes that group keys by hashing.
+	OrderedOutput bool
+
+	Mapper  Mapper[KeyIn, ValueIn, KeyOut, ValueMid]
+	Reducer Aggregator[KeyOut, ValueMid, ValueOut]
+	// MapperCtx and ReducerCtx are used instead of Mapper and Reducer
+	// when functions need the context of their thread.
+	MapperCtx  MapperCtx[KeyIn, ValueIn, KeyOut, ValueMid]
+	ReducerCtx AggregatorCtx[KeyOut, ValueMid, ValueOut]
+	// MapperFactory and ReducerFactory are used instead of all other
+	// mappers and reducers when every thread needs its own instance,
+	// with its own Setup and Cleanup.
+	MapperFactory  MapperFactory[KeyIn, ValueIn, KeyOut, ValueMid]
+	ReducerFactory AggregatorFactory[KeyOut, ValueMid, ValueOut]
+	// Combiner is used to combine values in mapping threads.
+	// If it is nil and Reducer returns the same type it receives,
+	// Reducer is used as combiner, unless DisableCombining is set.
+	Combiner         Combiner[KeyOut, ValueMid]
+	DisableCombining bool
+	Finalizer        Finalizer[KeyOut, ValueOut]
+	Filter           Filter[KeyOut, ValueOut]
 
 	Source    Source[KeyIn, ValueIn]
 	Collector Collector[KeyOut, ValueOut]
+	// Counters are counters that user functions increment.
+	// Values by which they were incremented during the run
+	// are returned in Stats and logged, so they can be shared
+	// by processes that run one after another, like runs of a job.
+	// If not set, the process creates its own counters,
+	// which user functions reach through TaskContext.
+	Counters *Counters
+	// SideInputs are loaded before mapping starts,
+	// so that mappers can read them.
+	SideInputs []SideInputLoader
+
+	// MapWorkers and ReduceWorkers are the numbers of mapping
+	// and maximal number of reducing threads.
+	// If they are not set, Parallelism is used to determine them.
+	MapWorkers    int
+	ReduceWorkers int
+	// Parallelism determines the number of threads for phases
+	// whose number of workers is not set.
+	// If it is nil, NumCPU is used.
+	Parallelism Parallelism
+
+	// MemoryLimit is an approximate number of bytes that emitted key-value
+	// pairs can occupy in memory. When it is exceeded, mapping threads sort,
+	// combine and spill their data to temporary files in SpillDir,
+	// which are then merged while reducing.
+	//
+	// Size of a pair is measured with PairSize. If it is not set, size of a pair
+	// is the size of key and value types together with contents of keys
+	// and values that are strings or byte slices, but without other memory
+	// they reference (like contents of slices in structs).
+	// If MemoryLimit is not set, all data is kept in memory.
+	MemoryLimit int
+	PairSize    func(key KeyOut, value ValueMid) int
+	// SpillDir is a directory in which temporary files are created.
+	// If it is empty, default directory for temporary files is used.
+	SpillDir string
+	// KeyCodec and ValueCodec are used to write spilled data to temporary files.
+	// If they are not set, GobCodec is used.
+	KeyCodec   Codec[KeyOut]
+	ValueCodec Codec[ValueMid]
+
+	// CheckpointDir is a directory in which progress of the process is persisted,
+	// so that a process that failed or was cancelled can be resumed by running
+	// a new process with the same configuration, source and CheckpointDir.
+	// If it is empty, progress is not persisted.
+	//
+	// Every CheckpointInterval records, mapping threads sort, combine and spill
+	// their data to CheckpointDir, and the number of mapped records is persisted.
+	// A resumed process skips that many records of Source, so Source must
+	// produce the same records in the same order on every run.
+	//
+	// If output is ordered and Collector is a ResumableCollector, the number of
+	// collected groups is persisted every CheckpointInterval groups as well,
+	// and a resumed process doesn't reduce them again.
+	// Otherwise, reducing starts from the beginning.
+	//
+	// Checkpoints are removed when the process finishes successfully.
+	// It is not supported by processes that group keys by hashing.
+	CheckpointDir string
+	// CheckpointInterval is the number of records, and of reduced groups,
+	// between two checkpoints. If it is not set, 100000 is used.
+	CheckpointInterval int
 
 	Logger *log.Logger
+	// ProgressInterval is the interval at which progress of the process
+	// is passed to OnProgress and logged. If it is not set, progress is not reported.
+	ProgressInterval time.Duration
+	OnProgress       func(progress Progress)
+	// SourceSize is the number of bytes that Source produces (like size of
+	// the file that is read), if it is known. It is used to report progress.
+	// Sources that report their size, like file sources, don't need it.
+	SourceSize int64
+	// StructuredLogger is used to log events of the process as structured
+	// records with stable messages (see LogProcessStarted and others)
+	// and attributes. It can be used instead of Logger or together with it.
+	// To log to a slog.Handler, wrap it with slog.New.
+	StructuredLogger *slog.Logger
+	LogLevels        LogLevels
+
+	// Observer is notified about the progress of the process.
+	Observer Observer[KeyOut, ValueOut]
 }
 
-var nextUid = 0
+// A Config is a configuration for a single MapReduce task
+// whose values are reduced to values of the same type.
+type Config[KeyIn, ValueIn, KeyOut, ValueOut any] = AggregationConfig[KeyIn, ValueIn, KeyOut, ValueOut, ValueOut]
 
-// A Process is an instance of a single MapReduce task.
+// An AggregationProcess is an instance of a single MapReduce task
+// whose mapped values are reduced to values of a different type.
 //
-// Zero value of Process has no configuration set and has invalid uid.
-type Process[KeyIn, ValueIn, KeyOut, ValueOut any] struct {
-	uid int
-
-	Config[KeyIn, ValueIn, KeyOut, ValueOut]
-
-	mappingThreads  []mappingThread[KeyIn, ValueIn, KeyOut, ValueOut]
-	reducingThreads []reducingThread[KeyIn, ValueIn, KeyOut, ValueOut]
-
-	mappedKeys   []KeyOut
-	mappedValues []ValueOut
+// Zero value of AggregationProcess has no configuration set and has invalid uid.
+type AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut any] struct {
+	uid     int
+	started time.Time
+	ran     atomic.Bool
+
+	AggregationConfig[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]
+
+	newMapper   MapperFactory[KeyIn, ValueIn, KeyOut, ValueMid]
+	newReducer  AggregatorFactory[KeyOut, ValueMid, ValueOut]
+	newCombiner AggregatorFactory[KeyOut, ValueMid, ValueMid]
+	passSingle  func(value ValueMid) ValueOut
+	// groupFilter decides which groups are reduced at all, before Reducer is called.
+	// If it is set, Reducer is called even for groups with a single value.
+	groupFilter func(key KeyOut, values []ValueMid) bool
+
+	mappingThreads   []mappingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]
+	intermediateRuns []string
+	reducingThreads  []reducingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]
+
+	hashGrouper   hashGrouper[KeyOut, ValueMid]
+	reorderBuffer *reorderBuffer[KeyOut, ValueOut]
+	checkpoint    checkpoint
 
 	collectingMutex sync.Mutex
-	linkBuffer      chan misc.Pair[KeyOut, ValueOut]
+	links           []processLink[KeyOut, ValueOut]
+
+	pipelineInput chan misc.Pair[KeyIn, ValueIn]
+	inputsLeft    atomic.Int32
 
 	processFinished sync.WaitGroup
+	stats           Stats
+	countersStart   map[string]int64
+	progress        progressCounters
 
-	runNext func()
+	errMutex sync.Mutex
+	err      error
+	cancel   context.CancelCauseFunc
+
+	runNext    func(ctx context.Context)
+	failLinked []func(err error)
 }
 
-// NewProcess creates a new Process with given configuration.
-func NewProcess[KeyIn, ValueIn, KeyOut, ValueOut any](config Config[KeyIn, ValueIn, KeyOut, ValueOut]) *Process[KeyIn, ValueIn, KeyOut, ValueOut] {
-	nextUid++
+// A Process is an instance of a single MapReduce task.
+//
+// Zero value of Process has no configuration set and has invalid uid.
+type Process[KeyIn, ValueIn, KeyOut, ValueOut any] = AggregationProcess[KeyIn, ValueIn, KeyOut, ValueOut, ValueOut]
 
-	process := &Process[KeyIn, ValueIn, KeyOut, ValueOut]{
-		uid: nextUid,
+// NewAggregationProcess creates a new AggregationProcess with given configuration.
+func NewAggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut any](
+	config AggregationConfig[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut],
+) *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut] {
+	process := &AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]{
+		uid: int(nextUid.Add(1)),
 
-		Config: config,
+		AggregationConfig: config,
 	}
 
 	process.processFinished.Add(1)
@@ -69,19 +223,32 @@ func NewProcess[KeyIn, ValueIn, KeyOut, ValueOut any](config Config[KeyIn, Value
 	return process
 }
 
+// NewDefaultAggregationProcess creates a new AggregationProcess
+// with default key comparator for ordered keys.
+func NewDefaultAggregationProcess[KeyIn, ValueIn any, KeyOut cmp.Ordered, ValueMid, ValueOut any](
+	config AggregationConfig[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut],
+) *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut] {
+	config.KeyComparator = cmp.Compare[KeyOut]
+
+	return NewAggregationProcess(config)
+}
+
+// NewProcess creates a new Process with given configuration.
+func NewProcess[KeyIn, ValueIn, KeyOut, ValueOut any](config Config[KeyIn, ValueIn, KeyOut, ValueOut]) *Process[KeyIn, ValueIn, KeyOut, ValueOut] {
+	return NewAggregationProcess(config)
+}
+
 // NewDefaultProcess creates a new Process with default key comparator for ordered keys.
 func NewDefaultProcess[KeyIn, ValueIn any, KeyOut cmp.Ordered, ValueOut any](
 	config Config[KeyIn, ValueIn, KeyOut, ValueOut],
 ) *Process[KeyIn, ValueIn, KeyOut, ValueOut] {
-	config.KeyComparator = cmp.Compare[KeyOut]
-
-	return NewProcess(config)
+	return NewDefaultAggregationProcess(config)
 }
 
 // Link links two processes together.
-func Link[KeyOld, ValueOld, KeyIn, ValueIn, KeyOut, ValueOut any](
-	prevProcess *Process[KeyOld, ValueOld, KeyIn, ValueIn],
-	nextProcess *Process[KeyIn, ValueIn, KeyOut, ValueOut],
+func Link[KeyOld, ValueOld, KeyIn, ValueMidOld, ValueIn, KeyOut, ValueMid, ValueOut any](
+	prevProcess *AggregationProcess[KeyOld, ValueOld, KeyIn, ValueMidOld, ValueIn],
+	nextProcess *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut],
 ) {
 	LinkWithBufferSize(prevProcess, nextProcess, 100)
 }
@@ -89,56 +256,236 @@ func Link[KeyOld, ValueOld, KeyIn, ValueIn, KeyOut, ValueOut any](
 // LinkWithBufferSize links two processes together with a buffer of given size.
 //
 // bufferSize is the size of the buffer that will be created to link the processes.
-func LinkWithBufferSize[KeyOld, ValueOld, KeyIn, ValueIn, KeyOut, ValueOut any](
-	prevProcess *Process[KeyOld, ValueOld, KeyIn, ValueIn],
-	nextProcess *Process[KeyIn, ValueIn, KeyOut, ValueOut],
+func LinkWithBufferSize[KeyOld, ValueOld, KeyIn, ValueMidOld, ValueIn, KeyOut, ValueMid, ValueOut any](
+	prevProcess *AggregationProcess[KeyOld, ValueOld, KeyIn, ValueMidOld, ValueIn],
+	nextProcess *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut],
 	bufferSize int,
 ) {
 	buffer := make(chan misc.Pair[KeyIn, ValueIn], bufferSize)
 
-	prevProcess.linkBuffer = buffer
-	nextProcess.Source = buffer
+	prevProcess.links = append(prevProcess.links, processLink[KeyIn, ValueIn]{
+		buffer:  buffer,
+		release: func() { close(buffer) },
+	})
+	nextProcess.Source = NewChannelSource[KeyIn, ValueIn](buffer)
+
+	prevProcess.runNext = func(ctx context.Context) {
+		// Collector of the next process is initialized and finalized
+		// in this goroutine, so its panics are returned as a JobError.
+		defer func() {
+			if value := recover(); value != nil {
+				nextProcess.fail(&JobError{Phase: PhaseCollect, Value: value, Stack: debug.Stack()})
+			}
+		}()
+
+		_ = nextProcess.RunContext(ctx)
+	}
 
-	prevProcess.runNext = nextProcess.Run
+	prevProcess.failLinked = append(prevProcess.failLinked, nextProcess.fail)
+	nextProcess.failLinked = append(nextProcess.failLinked, prevProcess.fail)
 }
 
 // Run starts the MapReduce task and blocks until it is finished.
 //
 // If logger is set, it will be used to log the progress.
-func (process *Process[KeyIn, ValueIn, KeyOut, ValueOut]) Run() {
-	if process.KeyComparator == nil {
-		panic("KeyComparator must be set")
+// Returned error is the first error that stopped the process.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) Run() error {
+	return process.RunContext(context.Background())
+}
+
+// RunContext starts the MapReduce task and blocks until it is finished
+// or until the given context is cancelled.
+//
+// Cancellation stops mapping threads, reducing threads and all
+// processes that are linked after this one. Collector is still
+// initialized and finalized, so that its consumers are unblocked,
+// and Source is stopped, so that its producer is not blocked.
+//
+// Returned error is the first error that stopped the process.
+// If a linked process fails, this process is stopped with the same error.
+//
+// A process can be run only once, further runs return ErrAlreadyRun.
+// Use a job to run the same task multiple times.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) RunContext(ctx context.Context) error {
+	if !process.ran.CompareAndSwap(false, true) {
+		return ErrAlreadyRun
 	}
 
-	if process.Mapper == nil {
-		panic("Mapper must be set")
+	defer process.processFinished.Done()
+
+	process.started = time.Now()
+	register(process.uid, process)
+	defer func() {
+		unregister(process.finishedInfo())
+	}()
+
+	if err := process.validate(); err != nil {
+		process.fail(err)
+		process.Source.stop()
+		process.releaseLinks()
+
+		process.observe(func(observer Observer[KeyOut, ValueOut]) {
+			observer.OnStart()
+			observer.OnFinish(process.stats, err)
+		})
+
+		if process.runNext != nil {
+			go process.runNext(ctx)
+		}
+
+		return err
 	}
 
-	if process.Reducer == nil {
-		panic("Reducer must be set")
+	process.resolveFunctions()
+	if process.Counters == nil {
+		process.Counters = NewCounters()
 	}
 
-	if process.Source == nil {
-		panic("Source must be set")
+	runStart := time.Now()
+	process.startCounters()
+	process.progress.enter(progressMapping)
+
+	if process.ProgressInterval > 0 {
+		reportingDone := make(chan struct{})
+		defer close(reportingDone)
+
+		go process.reportProgress(reportingDone)
 	}
 
-	if process.Collector == nil && process.linkBuffer == nil {
-		panic("Collector must be set")
+	runCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	process.errMutex.Lock()
+	process.cancel = cancel
+	if process.err != nil {
+		cancel(process.err)
 	}
+	process.errMutex.Unlock()
 
 	if process.Logger != nil {
-		process.Logger.Printf("Process %d: started\n", process.uid)
+		process.Logger.Printf("Process %s: started\n", process.label())
+	}
+	process.logEvent(process.LogLevels.process(), LogProcessStarted)
+	process.observe(func(observer Observer[KeyOut, ValueOut]) {
+		observer.OnStart()
+	})
+
+	if err := process.loadSideInputs(runCtx); err != nil {
+		process.fail(err)
+	}
+
+	if process.CheckpointDir != "" {
+		if err := process.loadCheckpoint(); err != nil {
+			process.fail(err)
+		}
+	}
+
+	process.mapData(runCtx)
+	if runCtx.Err() != nil {
+		process.Source.stop()
+	} else if err := process.Source.Err(); err != nil {
+		process.fail(err)
+	}
+	process.progress.enter(progressReducing)
+
+	if process.runNext != nil {
+		go process.runNext(ctx)
+	}
+
+	process.reduceData(runCtx)
+	process.removeCheckpoint(runCtx)
+	process.releaseMappedData()
+	process.mappingThreads = nil
+	process.reducingThreads = nil
+
+	if runCtx.Err() != nil {
+		process.fail(context.Cause(runCtx))
+
+		if process.Logger != nil {
+			process.Logger.Printf("Process %s: stopped: %v\n", process.label(), process.Err())
+		}
+	}
+
+	process.stats.TotalDuration = time.Since(runStart)
+	process.recordCounters()
+	process.progress.enter(progressFinished)
+	if process.OnProgress != nil {
+		process.OnProgress(process.Progress())
+	}
+
+	if err := process.Err(); err != nil {
+		process.logEvent(process.LogLevels.stop(), LogProcessStopped, slog.Any("error", err))
+	} else {
+		process.logFinished()
 	}
 
-	process.mapData()
-	process.reduceData()
+	process.observe(func(observer Observer[KeyOut, ValueOut]) {
+		observer.OnFinish(process.stats, process.Err())
+	})
 
-	process.processFinished.Done()
+	return process.Err()
 }
 
 // WaitToFinish blocks until the MapReduce task is finished.
-func (process *Process[KeyIn, ValueIn, KeyOut, ValueOut]) WaitToFinish() Collector[KeyOut, ValueOut] {
+//
+// It returns the collector that collected the data
+// and the error that stopped the process, if any.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) WaitToFinish() (Collector[KeyOut, ValueOut], error) {
 	process.processFinished.Wait()
 
-	return process.Collector
+	return process.Collector, process.Err()
+}
+
+// groupingComparator returns the comparator
+// that decides which keys belong to the same group.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) groupingComparator() comparison.Comparator[KeyOut] {
+	if process.GroupingComparator != nil {
+		return process.GroupingComparator
+	}
+
+	return process.KeyComparator
+}
+
+// resolveFunctions determines which functions are called by threads:
+// factories of per-thread mappers and reducers, and functions
+// in places where Reducer can be used only if its input and output types are the same.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) resolveFunctions() {
+	switch {
+	case process.MapperFactory != nil:
+		process.newMapper = process.MapperFactory
+	case process.MapperCtx != nil:
+		process.newMapper = statelessMapper(process.MapperCtx)
+	default:
+		process.newMapper = statelessMapper(AdaptMapper(process.Mapper))
+	}
+
+	switch {
+	case process.ReducerFactory != nil:
+		process.newReducer = process.ReducerFactory
+	case process.ReducerCtx != nil:
+		process.newReducer = statelessAggregator(process.ReducerCtx)
+	default:
+		process.newReducer = statelessAggregator(AdaptAggregator(process.Reducer))
+	}
+
+	newReducer, sameTypes := any(process.newReducer).(AggregatorFactory[KeyOut, ValueMid, ValueMid])
+
+	switch {
+	case process.DisableCombining:
+		process.newCombiner = nil
+	case process.Combiner != nil:
+		process.newCombiner = statelessAggregator(AdaptAggregator(Aggregator[KeyOut, ValueMid, ValueMid](process.Combiner)))
+	case sameTypes:
+		process.newCombiner = newReducer
+	default:
+		process.newCombiner = nil
+	}
+
+	if process.groupFilter != nil {
+		process.passSingle = nil
+	} else if pass, ok := any(func(value ValueMid) ValueMid { return value }).(func(value ValueMid) ValueOut); ok {
+		process.passSingle = pass
+	} else {
+		process.passSingle = nil
+	}
 }
@@ -0,0 +1,523 @@
+package meduce_test
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"github.com/djordje200179/extendedlibrary/misc"
+	"github.com/djordje200179/meduce"
+	"github.com/djordje200179/meduce/collectors"
+	"github.com/djordje200179/meduce/sources"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func numbers(count int) []int {
+	values := make([]int, count)
+	for i := range values {
+		values[i] = i
+	}
+
+	return values
+}
+
+func sum(_ int, values []int) int {
+	total := 0
+	for _, value := range values {
+		total += value
+	}
+
+	return total
+}
+
+// waitFor fails the test if the channel is not closed in a few seconds.
+func waitFor(t *testing.T, done <-chan struct{}, message string) {
+	t.Helper()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error(message)
+	}
+}
+
+func TestRunContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	records := make(chan misc.Pair[int, int])
+	produced := make(chan struct{})
+	go func() {
+		defer close(produced)
+		defer close(records)
+
+		for i := 0; i < 1_000_000; i++ {
+			records <- misc.Pair[int, int]{i, i}
+		}
+	}()
+
+	process := meduce.NewDefaultProcess(meduce.Config[int, int, int, int]{
+		Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+			if value == 1000 {
+				cancel()
+			}
+
+			emit(value%10, 1)
+		},
+		Reducer:   sum,
+		Source:    meduce.NewChannelSource(records),
+		Collector: collectors.NewMapCollector[int, int](),
+	})
+
+	if err := process.RunContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("RunContext() = %v, want %v", err, context.Canceled)
+	}
+
+	waitFor(t, produced, "source was not drained after the process was cancelled")
+}
+
+func TestRunContextCancelLinked(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first := meduce.NewDefaultProcess(meduce.Config[int, int, int, int]{
+		Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+			if value == 1000 {
+				cancel()
+			}
+
+			emit(value%10, 1)
+		},
+		Reducer: sum,
+		Source:  sources.NewSliceSource(numbers(100_000)),
+	})
+
+	second := meduce.NewDefaultProcess(meduce.Config[int, int, int, int]{
+		Mapper: func(key int, value int, emit meduce.Emitter[int, int]) {
+			emit(key, value)
+		},
+		Reducer:   sum,
+		Collector: collectors.NewMapCollector[int, int](),
+	})
+
+	meduce.Link(first, second)
+
+	if err := first.RunContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("RunContext() = %v, want %v", err, context.Canceled)
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		second.WaitToFinish()
+		close(finished)
+	}()
+
+	waitFor(t, finished, "linked process was not stopped")
+}
+
+func TestRunContextCancelProducer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	produced := make(chan struct{})
+	source := meduce.NewSource(func(writer meduce.SourceWriter[int, int]) error {
+		defer close(produced)
+
+		for i := 0; writer.Write(i, i); i++ {
+		}
+
+		return nil
+	})
+
+	process := meduce.NewDefaultProcess(meduce.Config[int, int, int, int]{
+		Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+			emit(value%10, 1)
+		},
+		Reducer:   sum,
+		Source:    source,
+		Collector: collectors.NewMapCollector[int, int](),
+	})
+
+	if err := process.RunContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("RunContext() = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	waitFor(t, produced, "source was not stopped after the process was cancelled")
+}
+
+func TestInvalidConfig(t *testing.T) {
+	process := meduce.NewDefaultProcess(meduce.Config[int, int, int, int]{
+		Reducer:   sum,
+		Source:    sources.NewSliceSource(numbers(10)),
+		Collector: collectors.NewMapCollector[int, int](),
+	})
+
+	if err := process.Run(); !errors.Is(err, meduce.ErrNoMapper) {
+		t.Errorf("Run() = %v, want %v", err, meduce.ErrNoMapper)
+	}
+}
+
+func TestSourceError(t *testing.T) {
+	errSource := errors.New("source failed")
+
+	process := meduce.NewDefaultProcess(meduce.Config[int, int, int, int]{
+		Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+			emit(value, 1)
+		},
+		Reducer: sum,
+		Source: meduce.NewSource(func(writer meduce.SourceWriter[int, int]) error {
+			writer.Write(0, 0)
+			return errSource
+		}),
+		Collector: collectors.NewMapCollector[int, int](),
+	})
+
+	if err := process.Run(); !errors.Is(err, errSource) {
+		t.Errorf("Run() = %v, want %v", err, errSource)
+	}
+}
+
+// failingCollector fails to collect the given key.
+type failingCollector struct {
+	collectors.MapCollector[int, int]
+
+	key       int
+	finalized bool
+}
+
+var errCollect = errors.New("collect failed")
+
+func (collector *failingCollector) Collect(key int, value int) error {
+	if key == collector.key {
+		return errCollect
+	}
+
+	return collector.MapCollector.Collect(key, value)
+}
+
+func (collector *failingCollector) Finalize() error {
+	collector.finalized = true
+	return nil
+}
+
+func TestCollectorError(t *testing.T) {
+	collector := &failingCollector{MapCollector: collectors.NewMapCollector[int, int](), key: 5}
+
+	process := meduce.NewDefaultProcess(meduce.Config[int, int, int, int]{
+		Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+			emit(value%10, 1)
+		},
+		Reducer:   sum,
+		Source:    sources.NewSliceSource(numbers(100)),
+		Collector: collector,
+	})
+
+	if err := process.Run(); !errors.Is(err, errCollect) {
+		t.Errorf("Run() = %v, want %v", err, errCollect)
+	}
+
+	if !collector.finalized {
+		t.Error("collector was not finalized after it failed")
+	}
+}
+
+// concurrency records the maximal number of goroutines that run a function at once.
+type concurrency struct {
+	active, max, entered atomic.Int32
+
+	expected   int32
+	allEntered chan struct{}
+}
+
+func newConcurrency(expected int32) *concurrency {
+	return &concurrency{expected: expected, allEntered: make(chan struct{})}
+}
+
+// enter records that a goroutine started running the function.
+// The first goroutines wait until the expected number of them entered,
+// so that the maximum is reached. Returned function records that it finished.
+func (c *concurrency) enter(t *testing.T) func() {
+	active := c.active.Add(1)
+	for max := c.max.Load(); active > max && !c.max.CompareAndSwap(max, active); max = c.max.Load() {
+	}
+
+	if entered := c.entered.Add(1); entered == c.expected {
+		close(c.allEntered)
+	} else if entered < c.expected {
+		select {
+		case <-c.allEntered:
+		case <-time.After(5 * time.Second):
+			t.Error("expected number of goroutines did not run at once")
+		}
+	}
+
+	return func() { c.active.Add(-1) }
+}
+
+func TestWorkers(t *testing.T) {
+	const mapWorkers, reduceWorkers = 3, 2
+
+	mappers := newConcurrency(mapWorkers)
+	finalizers := newConcurrency(reduceWorkers)
+
+	process := meduce.NewDefaultProcess(meduce.Config[int, int, int, int]{
+		Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+			defer mappers.enter(t)()
+
+			emit(value, value)
+		},
+		Reducer: sum,
+		Finalizer: func(_ int, _ *int) {
+			defer finalizers.enter(t)()
+		},
+		Source:        sources.NewSliceSource(numbers(100)),
+		Collector:     collectors.NewMapCollector[int, int](),
+		MapWorkers:    mapWorkers,
+		ReduceWorkers: reduceWorkers,
+	})
+
+	if err := process.Run(); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+
+	if max := mappers.max.Load(); max != mapWorkers {
+		t.Errorf("%d mapping threads ran at once, want %d", max, mapWorkers)
+	}
+
+	if max := finalizers.max.Load(); max != reduceWorkers {
+		t.Errorf("%d reducing threads ran at once, want %d", max, reduceWorkers)
+	}
+}
+
+func TestCombiner(t *testing.T) {
+	newProcesses := map[string]func(config meduce.Config[int, int, int, int]) *meduce.Process[int, int, int, int]{
+		"sorting": meduce.NewDefaultProcess[int, int, int, int],
+		"hashing": meduce.NewHashProcess[int, int, int, int, int],
+	}
+
+	for name, newProcess := range newProcesses {
+		t.Run(name, func(t *testing.T) {
+			var combinations atomic.Int32
+			collector := collectors.NewMapCollector[int, int]()
+
+			process := newProcess(meduce.Config[int, int, int, int]{
+				Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+					emit(value%10, 2)
+				},
+				// Reducer counts values, so that it can be
+				// told apart from the Combiner that sums them.
+				Reducer: func(_ int, values []int) int {
+					return len(values)
+				},
+				Combiner: func(key int, values []int) int {
+					combinations.Add(1)
+					return sum(key, values)
+				},
+				Source:     sources.NewSliceSource(numbers(1000)),
+				Collector:  collector,
+				MapWorkers: 1,
+			})
+
+			if err := process.Run(); err != nil {
+				t.Fatalf("Run() = %v", err)
+			}
+
+			if combinations.Load() == 0 {
+				t.Error("Combiner was not called")
+			}
+
+			for key := range 10 {
+				if collector[key] != 200 {
+					t.Errorf("key %d was reduced to %d, want 200", key, collector[key])
+				}
+			}
+		})
+	}
+}
+
+func TestDisableCombining(t *testing.T) {
+	newProcesses := map[string]func(config meduce.Config[int, int, int, int]) *meduce.Process[int, int, int, int]{
+		"sorting": meduce.NewDefaultProcess[int, int, int, int],
+		"hashing": meduce.NewHashProcess[int, int, int, int, int],
+	}
+
+	for name, newProcess := range newProcesses {
+		t.Run(name, func(t *testing.T) {
+			collector := collectors.NewMapCollector[int, int]()
+
+			process := newProcess(meduce.Config[int, int, int, int]{
+				Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+					emit(value%10, 1)
+				},
+				// Reducer counts values it gets, which are
+				// all emitted values if they are not combined.
+				Reducer: func(_ int, values []int) int {
+					return len(values)
+				},
+				DisableCombining: true,
+				Source:           sources.NewSliceSource(numbers(1000)),
+				Collector:        collector,
+				MapWorkers:       4,
+			})
+
+			if err := process.Run(); err != nil {
+				t.Fatalf("Run() = %v", err)
+			}
+
+			for key := range 10 {
+				if collector[key] != 100 {
+					t.Errorf("key %d got %d values, want 100", key, collector[key])
+				}
+			}
+		})
+	}
+}
+
+type partialAverage struct {
+	sum, count int
+}
+
+func TestAggregationProcess(t *testing.T) {
+	collector := collectors.NewMapCollector[int, float64]()
+
+	process := meduce.NewDefaultAggregationProcess(meduce.AggregationConfig[int, int, int, partialAverage, float64]{
+		Mapper: func(_ int, value int, emit meduce.Emitter[int, partialAverage]) {
+			// Key 10 gets a single value, so it must still be reduced.
+			if value == 999 {
+				emit(10, partialAverage{value, 1})
+			} else {
+				emit(value%10, partialAverage{value, 1})
+			}
+		},
+		Reducer: func(_ int, values []partialAverage) float64 {
+			var total partialAverage
+			for _, value := range values {
+				total.sum += value.sum
+				total.count += value.count
+			}
+
+			return float64(total.sum) / float64(total.count)
+		},
+		Combiner: func(_ int, values []partialAverage) partialAverage {
+			var total partialAverage
+			for _, value := range values {
+				total.sum += value.sum
+				total.count += value.count
+			}
+
+			return total
+		},
+		Source:     sources.NewSliceSource(numbers(1000)),
+		Collector:  collector,
+		MapWorkers: 4,
+	})
+
+	if err := process.Run(); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+
+	want := map[int]float64{10: 999}
+	for key := range 10 {
+		want[key] = float64(495 + key)
+	}
+	// Without 999, key 9 averages 9, 19, ..., 989.
+	want[9] = 499
+
+	if len(collector) != len(want) {
+		t.Errorf("got %d keys, want %d", len(collector), len(want))
+	}
+	for key, average := range want {
+		if collector[key] != average {
+			t.Errorf("key %d was reduced to %v, want %v", key, collector[key], average)
+		}
+	}
+}
+
+type event struct {
+	user, time int
+}
+
+func TestGroupingComparator(t *testing.T) {
+	collector := collectors.NewMapCollector[event, int]()
+
+	process := meduce.NewProcess(meduce.Config[int, int, event, int]{
+		KeyComparator: func(first, second event) int {
+			if first.user != second.user {
+				return cmp.Compare(first.user, second.user)
+			}
+
+			return cmp.Compare(first.time, second.time)
+		},
+		GroupingComparator: func(first, second event) int {
+			return cmp.Compare(first.user, second.user)
+		},
+		Mapper: func(_ int, value int, emit meduce.Emitter[event, int]) {
+			emit(event{value % 5, 100 - value}, value)
+		},
+		// Reducer counts values if they are ordered by time,
+		// which means in descending order of emitted values.
+		Reducer: func(_ event, values []int) int {
+			for i := 1; i < len(values); i++ {
+				if values[i-1] <= values[i] {
+					return -1
+				}
+			}
+
+			return len(values)
+		},
+		DisableCombining: true,
+		Source:           sources.NewSliceSource(numbers(100)),
+		Collector:        collector,
+		MapWorkers:       4,
+		ReduceWorkers:    3,
+	})
+
+	if err := process.Run(); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+
+	if len(collector) != 5 {
+		t.Errorf("got %d groups, want 5", len(collector))
+	}
+	for key, count := range collector {
+		if count != 20 {
+			t.Errorf("group of user %d got %d ordered values, want 20", key.user, count)
+		}
+		if key.time != 100-(95+key.user) {
+			t.Errorf("group of user %d has key time %d, want the earliest one", key.user, key.time)
+		}
+	}
+}
+
+func TestOrderedOutput(t *testing.T) {
+	collector := collectors.NewChannelCollector[int, int](100)
+
+	process := meduce.NewDefaultProcess(meduce.Config[int, int, int, int]{
+		Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+			emit(value%100, 1)
+		},
+		Reducer:       sum,
+		Source:        sources.NewSliceSource(numbers(10_000)),
+		Collector:     collector,
+		MapWorkers:    4,
+		ReduceWorkers: 8,
+		OrderedOutput: true,
+	})
+
+	if err := process.Run(); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+
+	key := 0
+	for pair := range collector.Get() {
+		if pair.First != key || pair.Second != 100 {
+			t.Errorf("collected (%d, %d), want (%d, 100)", pair.First, pair.Second, key)
+		}
+		key++
+	}
+
+	if key != 100 {
+		t.Errorf("collected %d pairs, want 100", key)
+	}
+}
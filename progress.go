@@ -0,0 +1,191 @@
+package meduce
+
+import (
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// Progress is a snapshot of the progress of a process.
+type Progress struct {
+	Started  bool  // Started reports whether the process was started
+	Finished bool  // Finished reports whether the process is finished
+	Phase    Phase // Phase is PhaseMap while data is mapped, and PhaseReduce while it is reduced
+
+	RecordsMapped int // RecordsMapped is the number of records read from Source and mapped
+	// BytesRead is the number of bytes that Source reported as read, or if it doesn't
+	// report them, the total length of string and byte slice keys and values read.
+	BytesRead int64
+	// SourceSize is the number of bytes that Source produces, if it is known.
+	SourceSize int64
+
+	GroupsReduced int // GroupsReduced is the number of groups that were reduced
+	// GroupsEstimate is the estimated number of groups to reduce.
+	// It is known only in the reduce phase.
+	GroupsEstimate int
+
+	Elapsed time.Duration // Elapsed is the time since the process was started
+	// ETA is the estimated time until the current phase is finished.
+	// It is zero if it can't be estimated.
+	ETA time.Duration
+}
+
+// Fraction returns the finished fraction of the current phase,
+// or -1 if it can't be determined.
+func (progress Progress) Fraction() float64 {
+	switch {
+	case progress.Finished:
+		return 1
+	case !progress.Started:
+		return 0
+	case progress.Phase == PhaseMap && progress.SourceSize > 0:
+		return min(float64(progress.BytesRead)/float64(progress.SourceSize), 1)
+	case progress.Phase == PhaseReduce && progress.GroupsEstimate > 0:
+		return min(float64(progress.GroupsReduced)/float64(progress.GroupsEstimate), 1)
+	default:
+		return -1
+	}
+}
+
+func (progress Progress) String() string {
+	switch {
+	case progress.Finished:
+		return "finished"
+	case !progress.Started:
+		return "not started"
+	case progress.Phase == PhaseMap && progress.SourceSize > 0:
+		return fmt.Sprintf("map phase: %d records mapped, %d of %d bytes read, ETA %v",
+			progress.RecordsMapped, progress.BytesRead, progress.SourceSize, progress.ETA.Round(time.Second))
+	case progress.Phase == PhaseMap:
+		return fmt.Sprintf("map phase: %d records mapped", progress.RecordsMapped)
+	default:
+		return fmt.Sprintf("reduce phase: %d of %d groups reduced, ETA %v",
+			progress.GroupsReduced, progress.GroupsEstimate, progress.ETA.Round(time.Second))
+	}
+}
+
+const (
+	progressNotStarted = iota
+	progressMapping
+	progressReducing
+	progressFinished
+)
+
+// progressCounters are updated by threads of the process
+// while it is running, so they are read and written atomically.
+type progressCounters struct {
+	state      atomic.Int32
+	start      atomic.Int64 // start is the time when the process was started, in Unix nanoseconds
+	phaseStart atomic.Int64 // phaseStart is the time when the current phase was started, in Unix nanoseconds
+
+	recordsMapped  atomic.Int64
+	bytesRead      atomic.Int64
+	groupsReduced  atomic.Int64
+	groupsEstimate atomic.Int64
+}
+
+func (counters *progressCounters) enter(state int32) {
+	now := time.Now().UnixNano()
+	if state == progressMapping {
+		counters.start.Store(now)
+	}
+	counters.phaseStart.Store(now)
+	counters.state.Store(state)
+}
+
+// Progress returns a snapshot of the progress of the process.
+// It can be called while the process is running.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) Progress() Progress {
+	counters := &process.progress
+
+	state := counters.state.Load()
+	progress := Progress{
+		Started:  state != progressNotStarted,
+		Finished: state == progressFinished,
+
+		RecordsMapped: int(counters.recordsMapped.Load()),
+		BytesRead:     process.bytesRead(),
+		SourceSize:    process.sourceSize(),
+
+		GroupsReduced:  int(counters.groupsReduced.Load()),
+		GroupsEstimate: int(counters.groupsEstimate.Load()),
+	}
+
+	if state == progressReducing {
+		progress.Phase = PhaseReduce
+	} else {
+		progress.Phase = PhaseMap
+	}
+
+	if !progress.Started {
+		return progress
+	} else if progress.Finished {
+		progress.Elapsed = process.stats.TotalDuration
+		return progress
+	}
+
+	now := time.Now()
+	progress.Elapsed = now.Sub(time.Unix(0, counters.start.Load()))
+	phaseElapsed := now.Sub(time.Unix(0, counters.phaseStart.Load()))
+
+	if fraction := progress.Fraction(); fraction > 0 && fraction < 1 {
+		progress.ETA = time.Duration(float64(phaseElapsed) * (1 - fraction) / fraction)
+	}
+
+	return progress
+}
+
+// sourceSize returns SourceSize, or if it is not set, the size reported by Source.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) sourceSize() int64 {
+	if process.SourceSize > 0 {
+		return process.SourceSize
+	}
+
+	return process.Source.state.Size()
+}
+
+// bytesRead returns the number of bytes that Source reported as read,
+// or the length of keys and values read by mapping threads.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) bytesRead() int64 {
+	if bytesRead := process.Source.state.BytesRead(); bytesRead > 0 {
+		return bytesRead
+	}
+
+	return process.progress.bytesRead.Load()
+}
+
+// reportProgress periodically passes progress of the process
+// to OnProgress and loggers, until the done channel is closed.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) reportProgress(done <-chan struct{}) {
+	ticker := time.NewTicker(process.ProgressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			progress := process.Progress()
+
+			if process.OnProgress != nil {
+				process.OnProgress(progress)
+			}
+
+			if process.Logger != nil {
+				process.Logger.Printf("Process %s: %v\n", process.label(), progress)
+			}
+			process.logEvent(
+				process.LogLevels.process(), LogProcessProgress,
+				slog.String("phase", progress.Phase.String()),
+				slog.Int("records_mapped", progress.RecordsMapped),
+				slog.Int64("bytes_read", progress.BytesRead),
+				slog.Int64("source_size", progress.SourceSize),
+				slog.Int("groups_reduced", progress.GroupsReduced),
+				slog.Int("groups_estimate", progress.GroupsEstimate),
+				slog.Duration("elapsed", progress.Elapsed),
+				slog.Duration("eta", progress.ETA),
+			)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package meduce_test
+
+import (
+	"github.com/djordje200179/meduce"
+	"github.com/djordje200179/meduce/collectors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProgress(t *testing.T) {
+	proceed := make(chan struct{})
+	source := meduce.NewSource(func(writer meduce.SourceWriter[int, int]) error {
+		writer.SetSize(100)
+
+		for i := range 10 {
+			if i == 5 {
+				<-proceed
+			}
+
+			writer.AddBytesRead(10)
+			if !writer.Write(i, i) {
+				return nil
+			}
+		}
+
+		return nil
+	})
+
+	var reports atomic.Int32
+	process := meduce.NewDefaultProcess(meduce.Config[int, int, int, int]{
+		Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+			emit(value%2, 1)
+		},
+		Reducer:          sum,
+		Source:           source,
+		Collector:        collectors.NewMapCollector[int, int](),
+		ProgressInterval: time.Millisecond,
+		OnProgress: func(meduce.Progress) {
+			reports.Add(1)
+		},
+	})
+
+	if progress := process.Progress(); progress.Started || progress.Fraction() != 0 {
+		t.Errorf("Progress() = %v before Run, want not started", progress)
+	}
+
+	go process.Run()
+
+	deadline := time.Now().Add(5 * time.Second)
+	progress := process.Progress()
+	for progress.RecordsMapped < 5 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+		progress = process.Progress()
+	}
+
+	if progress.Phase != meduce.PhaseMap || progress.BytesRead != 50 || progress.SourceSize != 100 {
+		t.Errorf("Progress() = %+v, want 50 of 100 bytes read in the map phase", progress)
+	}
+	if progress.Fraction() != 0.5 {
+		t.Errorf("Fraction() = %v, want 0.5", progress.Fraction())
+	}
+
+	close(proceed)
+
+	if _, err := process.WaitToFinish(); err != nil {
+		t.Fatalf("WaitToFinish() = %v", err)
+	}
+
+	progress = process.Progress()
+	if !progress.Finished || progress.Fraction() != 1 || progress.RecordsMapped != 10 {
+		t.Errorf("Progress() = %+v after Run, want 10 records mapped and finished", progress)
+	}
+
+	if bytesRead := process.Stats().BytesRead; bytesRead != 100 {
+		t.Errorf("Stats().BytesRead = %d, want the 100 bytes reported by Source", bytesRead)
+	}
+
+	if reports.Load() == 0 {
+		t.Error("OnProgress was not called")
+	}
+}
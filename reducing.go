@@ -1,80 +1,234 @@
 package meduce
 
 import (
+	"context"
 	"github.com/djordje200179/extendedlibrary/misc"
+	"log/slog"
 	"reflect"
-	"runtime"
 	"sync"
+	"time"
 )
 
-func (process *Process[KeyIn, ValueIn, KeyOut, ValueOut]) reduceData() {
+// readyGroupsPerThread is the number of groups that are
+// merged in advance for each of the reducing threads.
+const readyGroupsPerThread = 64
+
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) reduceData(ctx context.Context) {
+	reduceStart := time.Now()
+	defer func() {
+		process.recordReducingStats(time.Since(reduceStart))
+	}()
+
+	defer process.releaseLinks()
+
+	if process.Collector != nil {
+		if err := process.Collector.Init(); err != nil {
+			process.fail(err)
+			return
+		}
+
+		defer func() {
+			if err := process.Collector.Finalize(); err != nil {
+				process.fail(err)
+			}
+		}()
+
+		if err := process.resumeCollector(); err != nil {
+			process.fail(err)
+			return
+		}
+	}
+
 	groupsCount := process.estimateGroupsCount()
+	process.progress.groupsEstimate.Store(int64(groupsCount))
 
-	var threadsCount int
-	if groupsCount > runtime.NumCPU() {
-		threadsCount = runtime.NumCPU()
-	} else {
+	threadsCount := process.workers(process.ReduceWorkers)
+	if groupsCount < threadsCount {
 		threadsCount = groupsCount
 	}
 
-	readyDataPool := make(chan reducingDataGroup[KeyOut, ValueOut], groupsCount)
-	go reducingDataGenerationThread(
-		process.KeyComparator,
-		process.mappedKeys, process.mappedValues,
-		readyDataPool,
-	)
+	readyDataPool := make(chan reducingDataGroup[KeyOut, ValueMid], threadsCount*readyGroupsPerThread)
+
+	if process.OrderedOutput {
+		process.reorderBuffer = newReorderBuffer(
+			ctx, threadsCount*readyGroupsPerThread,
+			process.skippedGroups(),
+			process.collectUnordered(ctx),
+		)
+		if _, ok := process.resumableCollector(); ok {
+			process.reorderBuffer.checkpoint = process.checkpointGroups
+			process.reorderBuffer.checkpointInterval = process.checkpointInterval()
+		}
+		defer func() {
+			process.reorderBuffer = nil
+		}()
+	}
 
-	if process.Collector != nil {
-		process.Collector.Init()
-		defer process.Collector.Finalize()
-	} else {
-		go process.runNext()
-		defer close(process.linkBuffer)
+	var allGeneratorsFinished sync.WaitGroup
+	if err := process.generateReducingData(ctx, threadsCount, readyDataPool, &allGeneratorsFinished); err != nil {
+		process.fail(err)
+		return
 	}
 
+	mergeDone := make(chan struct{})
+	go func() {
+		defer close(mergeDone)
+
+		allGeneratorsFinished.Wait()
+		close(readyDataPool)
+
+		if ctx.Err() == nil {
+			process.observe(func(observer Observer[KeyOut, ValueOut]) {
+				observer.OnMergeDone()
+			})
+		}
+	}()
+
 	var barrier sync.WaitGroup
 	barrier.Add(threadsCount)
 
-	process.reducingThreads = make([]reducingThread[KeyIn, ValueIn, KeyOut, ValueOut], threadsCount)
+	process.reducingThreads = make([]reducingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut], threadsCount)
 	for i := range process.reducingThreads {
-		process.reducingThreads[i].Process = process
+		process.reducingThreads[i].AggregationProcess = process
+		process.reducingThreads[i].index = i
 
-		go process.reducingThreads[i].run(readyDataPool, &barrier)
+		go process.reducingThreads[i].run(ctx, readyDataPool, &barrier)
 	}
 
 	if process.Logger != nil {
 		var message string
 		if threadsCount == 1 {
-			message = "Process %d: %d reducing thread was started\n"
+			message = "Process %s: %d reducing thread was started\n"
 		} else {
-			message = "Process %d: %d reducing threads were started\n"
+			message = "Process %s: %d reducing threads were started\n"
 		}
 
-		process.Logger.Printf(message, process.uid, threadsCount)
+		process.Logger.Printf(message, process.label(), threadsCount)
 	}
+	process.logEvent(
+		process.LogLevels.process(), LogReducingStarted,
+		slog.String("phase", PhaseReduce.String()),
+		slog.Int("threads", threadsCount),
+		slog.Bool("ordered", process.OrderedOutput),
+	)
 
 	barrier.Wait()
+	<-mergeDone
 
 	if process.Logger != nil {
-		process.Logger.Printf("Process %d: all reducing threads finished\n", process.uid)
+		process.Logger.Printf("Process %s: all reducing threads finished\n", process.label())
 	}
+	process.logEvent(
+		process.LogLevels.process(), LogReducingFinished,
+		slog.String("phase", PhaseReduce.String()),
+		slog.Duration("duration", time.Since(reduceStart)),
+	)
 }
 
-func (process *Process[KeyIn, ValueIn, KeyOut, ValueOut]) collect(key KeyOut, value ValueOut) {
-	if process.Collector == nil {
-		process.linkBuffer <- misc.Pair[KeyOut, ValueOut]{key, value}
-		return
+// generateReducingData starts threads that group mapped data
+// and send the groups to reducing threads.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) generateReducingData(
+	ctx context.Context,
+	threadsCount int,
+	readyDataPool chan<- reducingDataGroup[KeyOut, ValueMid],
+	finishSignal *sync.WaitGroup,
+) error {
+	if process.hashGrouper != nil && len(process.mappingThreads) > 0 {
+		tables := make([]hashTable[KeyOut, ValueMid], len(process.mappingThreads))
+		for i, thread := range process.mappingThreads {
+			tables[i] = thread.table
+		}
+
+		shardsCount := tables[0].shardsCount()
+		finishSignal.Add(shardsCount)
+
+		for shard := 0; shard < shardsCount; shard++ {
+			go hashDataGenerationThread(
+				ctx,
+				process.hashGrouper,
+				process.ValueComparator,
+				tables, shard,
+				readyDataPool,
+				process.fail,
+				finishSignal,
+			)
+		}
+
+		return nil
+	}
+
+	// Groups are numbered in sequence by a single merger,
+	// so that the reorder buffer can restore their order
+	// and groups collected before a resume can be skipped.
+	rangesCount := threadsCount
+	if process.OrderedOutput {
+		rangesCount = 1
+	}
+
+	mergers, err := process.createMergers(ctx, rangesCount)
+	if err != nil {
+		return err
+	}
+
+	finishSignal.Add(len(mergers))
+
+	for i, merger := range mergers {
+		go reducingDataGenerationThread(
+			ctx, i,
+			process.groupingComparator(),
+			merger,
+			process.skippedGroups(),
+			readyDataPool,
+			process.fail,
+			finishSignal,
+		)
 	}
 
+	return nil
+}
+
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) collect(ctx context.Context, key KeyOut, value ValueOut) error {
+	for _, link := range process.links {
+		select {
+		case link.buffer <- misc.Pair[KeyOut, ValueOut]{key, value}:
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+	}
+
+	if process.Collector != nil {
+		if err := process.collectLocally(key, value); err != nil {
+			return err
+		}
+	}
+
+	process.observeCollect(key, value)
+
+	return nil
+}
+
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) collectLocally(key KeyOut, value ValueOut) error {
 	if reflect.TypeOf(process.Collector).Kind() != reflect.Chan {
 		process.collectingMutex.Lock()
 		defer process.collectingMutex.Unlock()
 	}
 
-	process.Collector.Collect(key, value)
+	return process.Collector.Collect(key, value)
+}
+
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) observeCollect(key KeyOut, value ValueOut) {
+	if process.Observer != nil {
+		process.Observer.OnCollect(key, value)
+	}
+}
+
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) collectUnordered(ctx context.Context) func(key KeyOut, value ValueOut) error {
+	return func(key KeyOut, value ValueOut) error {
+		return process.collect(ctx, key, value)
+	}
 }
 
-func (process *Process[KeyIn, ValueIn, KeyOut, ValueOut]) estimateGroupsCount() int {
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) estimateGroupsCount() int {
 	var combinationsCount int
 
 	for _, thread := range process.mappingThreads {
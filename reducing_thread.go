@@ -1,90 +1,212 @@
 package meduce
 
 import (
+	"context"
 	"fmt"
 	"github.com/djordje200179/extendedlibrary/misc/functions/comparison"
 	"strings"
 	"sync"
+	"time"
 )
 
-type reducingDataGroup[KeyOut, ValueOut any] struct {
-	key    KeyOut
-	values []ValueOut
+type reducingDataGroup[KeyOut, ValueMid any] struct {
+	key      KeyOut
+	values   []ValueMid
+	sequence int
 }
 
-type reducingThread[KeyIn, ValueIn, KeyOut, ValueOut any] struct {
-	*Process[KeyIn, ValueIn, KeyOut, ValueOut]
+type reducingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut any] struct {
+	*AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]
+	threadState[KeyIn, KeyOut]
+	task TaskContext
+
+	reducer ThreadAggregator[KeyOut, ValueMid, ValueOut]
 
 	reductionsCount  int
 	collectionsCount int
+	filteredCount    int
+
+	reduceDuration time.Duration
 }
 
-func (thread *reducingThread[KeyIn, ValueIn, KeyOut, ValueOut]) run(
-	dataPool <-chan reducingDataGroup[KeyOut, ValueOut],
+func (thread *reducingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) run(
+	ctx context.Context,
+	dataPool <-chan reducingDataGroup[KeyOut, ValueMid],
 	finishSignal *sync.WaitGroup,
 ) {
+	defer finishSignal.Done()
+	defer thread.cleanup()
+	defer thread.recoverPanic()
+
+	thread.task = thread.newTaskContext(ctx, PhaseReduce, thread.index)
+	if !thread.setup() {
+		return
+	}
+
+	reduceStart := time.Now()
+
 	for groupData := range dataPool {
-		var reducedValue ValueOut
-		if len(groupData.values) == 1 {
-			reducedValue = groupData.values[0]
-		} else {
-			reducedValue = thread.Reducer(groupData.key, groupData.values)
+		if ctx.Err() != nil {
+			break
 		}
 
-		thread.reductionsCount++
+		thread.enter(PhaseReduce)
+		thread.setOutputKey(groupData.key)
 
-		if thread.Finalizer != nil {
-			thread.Finalizer(groupData.key, &reducedValue)
+		if thread.Observer != nil {
+			thread.phase = PhaseObserve
+			thread.Observer.OnReduceGroup(thread.index, groupData.key, len(groupData.values))
+			thread.phase = PhaseReduce
 		}
 
-		if thread.Filter == nil || thread.Filter(groupData.key, &reducedValue) {
-			thread.collect(groupData.key, reducedValue)
+		reducedValue, keep := thread.reduceGroup(groupData.key, groupData.values)
+		thread.progress.groupsReduced.Add(1)
+
+		thread.phase = PhaseCollect
+		if err := thread.deliver(ctx, groupData.sequence, groupData.key, reducedValue, keep); err != nil {
+			thread.fail(err)
+			break
+		}
+
+		if keep {
 			thread.collectionsCount++
 		}
 	}
 
+	thread.reduceDuration = time.Since(reduceStart)
+	thread.logFinished()
+
 	if thread.Logger != nil {
 		var sb strings.Builder
 
-		sb.WriteString(fmt.Sprintf("Process %d: reducing thread finished\n", thread.uid))
+		sb.WriteString(fmt.Sprintf("Process %s: reducing thread finished\n", thread.label()))
 		sb.WriteString(fmt.Sprintf("\t%d reductions finished\n", thread.reductionsCount))
-		sb.WriteString(fmt.Sprintf("\t%d collections finished\n", thread.reductionsCount))
+		sb.WriteString(fmt.Sprintf("\t%d collections finished\n", thread.collectionsCount))
+		sb.WriteString(fmt.Sprintf("\t%d keys filtered out\n", thread.filteredCount))
 
 		thread.Logger.Print(sb.String())
 	}
+}
+
+// reduceGroup reduces, finalizes and filters values of a single group.
+// It reports whether the reduced value should be collected.
+// Groups dropped by groupFilter are not counted as reduced or filtered out.
+func (thread *reducingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) reduceGroup(key KeyOut, values []ValueMid) (ValueOut, bool) {
+	var reducedValue ValueOut
+
+	if thread.groupFilter != nil && !thread.groupFilter(key, values) {
+		return reducedValue, false
+	}
+
+	if len(values) == 1 && thread.passSingle != nil {
+		reducedValue = thread.passSingle(values[0])
+	} else {
+		reducedValue = thread.reducer.Reduce(&thread.task, key, values)
+	}
+
+	thread.reductionsCount++
 
-	finishSignal.Done()
+	if thread.Finalizer != nil {
+		thread.phase = PhaseFinalize
+		thread.Finalizer(key, &reducedValue)
+	}
+
+	thread.phase = PhaseFilter
+	keep := thread.Filter == nil || thread.Filter(key, &reducedValue)
+	if !keep {
+		thread.filteredCount++
+	}
+
+	return reducedValue, keep
 }
 
-func reducingDataGenerationThread[KeyOut, ValueOut any](
-	keyComparator comparison.Comparator[KeyOut],
-	mappedKeys []KeyOut, mappedValues []ValueOut,
-	readyDataPool chan<- reducingDataGroup[KeyOut, ValueOut],
+// setup creates the reducer of the thread and sets it up.
+// It reports whether it is ready to be used.
+func (thread *reducingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) setup() bool {
+	thread.enter(PhaseReduce)
+
+	reducer := thread.newReducer()
+	if err := reducer.Setup(&thread.task); err != nil {
+		thread.fail(err)
+		return false
+	}
+	thread.reducer = reducer
+
+	return true
+}
+
+// cleanup cleans up the reducer of the thread, if it was set up.
+// It runs after panics of the thread have been recovered,
+// so that they are reported with the phase in which they happened.
+func (thread *reducingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) cleanup() {
+	defer thread.recoverPanic()
+
+	if thread.reducer == nil {
+		return
+	}
+
+	thread.enter(PhaseReduce)
+	if err := thread.reducer.Cleanup(&thread.task); err != nil {
+		thread.fail(err)
+	}
+}
+
+func (thread *reducingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) recoverPanic() {
+	if value := recover(); value != nil {
+		thread.fail(thread.jobError(value))
+	}
+}
+
+func reducingDataGenerationThread[KeyOut, ValueMid any](
+	ctx context.Context,
+	index int,
+	groupingComparator comparison.Comparator[KeyOut],
+	merger *runsMerger[KeyOut, ValueMid],
+	skippedGroups int,
+	readyDataPool chan<- reducingDataGroup[KeyOut, ValueMid],
+	fail func(err error),
+	finishSignal *sync.WaitGroup,
 ) {
-	lastIndex := -1
-	for i := 1; i <= len(mappedKeys); i++ {
-		lastKey := mappedKeys[i-1]
+	defer finishSignal.Done()
+	defer merger.close()
 
-		if i != len(mappedKeys) {
-			currentKey := mappedKeys[i]
+	state := threadState[any, KeyOut]{index: index, phase: PhaseMerge}
+	defer state.recoverPanic(fail)
 
-			if keyComparator(lastKey, currentKey) == comparison.Equal {
-				continue
-			}
+	sequence := 0
+
+	key, value, ok := merger.next()
+	for ok {
+		state.setOutputKey(key)
+
+		reducerData := reducingDataGroup[KeyOut, ValueMid]{
+			key:      key,
+			values:   []ValueMid{value},
+			sequence: sequence,
 		}
+		sequence++
 
-		firstIndex := lastIndex + 1
-		lastIndex = i - 1
+		for {
+			key, value, ok = merger.next()
+			if !ok || groupingComparator(reducerData.key, key) != comparison.Equal {
+				break
+			}
 
-		validValues := mappedValues[firstIndex : lastIndex+1]
+			reducerData.values = append(reducerData.values, value)
+		}
 
-		reducerData := reducingDataGroup[KeyOut, ValueOut]{
-			key:    lastKey,
-			values: validValues,
+		if reducerData.sequence < skippedGroups {
+			continue
 		}
 
-		readyDataPool <- reducerData
+		select {
+		case readyDataPool <- reducerData:
+		case <-ctx.Done():
+			return
+		}
 	}
 
-	close(readyDataPool)
+	if err := merger.err(); err != nil {
+		fail(err)
+	}
 }
@@ -0,0 +1,143 @@
+package meduce
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var nextUid atomic.Int64
+
+// finishedProcessesLimit is the number of finished processes
+// that are kept in the registry.
+const finishedProcessesLimit = 100
+
+// A ProcessStatus is the state of a process in the registry.
+type ProcessStatus int
+
+const (
+	StatusRunning   ProcessStatus = iota // StatusRunning is the status of a process that is running
+	StatusSucceeded                      // StatusSucceeded is the status of a process that finished without errors
+	StatusFailed                         // StatusFailed is the status of a process that was stopped by an error
+)
+
+var statusNames = [...]string{"running", "succeeded", "failed"}
+
+func (status ProcessStatus) String() string {
+	if status < 0 || int(status) >= len(statusNames) {
+		return fmt.Sprintf("ProcessStatus(%d)", int(status))
+	}
+
+	return statusNames[status]
+}
+
+// ProcessInfo describes a process in the registry.
+type ProcessInfo struct {
+	Uid    int
+	Name   string
+	Status ProcessStatus
+	Err    error // Err is the error that stopped the process, if it failed
+
+	Started  time.Time
+	Finished time.Time // Finished is zero while the process is running
+
+	Progress Progress
+}
+
+type registeredProcess interface {
+	info() ProcessInfo
+}
+
+var registry = struct {
+	mutex    sync.Mutex
+	running  map[int]registeredProcess
+	finished []ProcessInfo
+}{
+	running: make(map[int]registeredProcess),
+}
+
+// Processes returns descriptions of processes that are running,
+// and of the last finished ones, ordered by their uids.
+//
+// Processes are added to the registry when they are started.
+func Processes() []ProcessInfo {
+	registry.mutex.Lock()
+	infos := slices.Clone(registry.finished)
+	running := make([]registeredProcess, 0, len(registry.running))
+	for _, process := range registry.running {
+		running = append(running, process)
+	}
+	registry.mutex.Unlock()
+
+	for _, process := range running {
+		infos = append(infos, process.info())
+	}
+
+	slices.SortFunc(infos, func(first, second ProcessInfo) int {
+		return first.Uid - second.Uid
+	})
+
+	return infos
+}
+
+func register(uid int, process registeredProcess) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	registry.running[uid] = process
+}
+
+func unregister(info ProcessInfo) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	delete(registry.running, info.Uid)
+
+	registry.finished = append(registry.finished, info)
+	if len(registry.finished) > finishedProcessesLimit {
+		registry.finished = slices.Delete(registry.finished, 0, len(registry.finished)-finishedProcessesLimit)
+	}
+}
+
+// Uid returns the unique identifier of the process.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) Uid() int {
+	return process.uid
+}
+
+// label returns the uid of the process with its name, if it is set.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) label() string {
+	if process.Name == "" {
+		return fmt.Sprint(process.uid)
+	}
+
+	return fmt.Sprintf("%d (%s)", process.uid, process.Name)
+}
+
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) info() ProcessInfo {
+	info := ProcessInfo{
+		Uid:      process.uid,
+		Name:     process.Name,
+		Status:   StatusRunning,
+		Started:  process.started,
+		Progress: process.Progress(),
+	}
+
+	return info
+}
+
+// finishedInfo describes the process after it is finished.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) finishedInfo() ProcessInfo {
+	info := process.info()
+	info.Finished = time.Now()
+	info.Err = process.Err()
+
+	if info.Err != nil {
+		info.Status = StatusFailed
+	} else {
+		info.Status = StatusSucceeded
+	}
+
+	return info
+}
@@ -0,0 +1,80 @@
+package meduce_test
+
+import (
+	"errors"
+	"github.com/djordje200179/meduce"
+	"github.com/djordje200179/meduce/collectors"
+	"github.com/djordje200179/meduce/sources"
+	"testing"
+)
+
+// processInfo returns the description of the process with the uid from the registry.
+func processInfo(t *testing.T, uid int) meduce.ProcessInfo {
+	t.Helper()
+
+	for _, info := range meduce.Processes() {
+		if info.Uid == uid {
+			return info
+		}
+	}
+
+	t.Fatalf("process %d is not in the registry", uid)
+	return meduce.ProcessInfo{}
+}
+
+func TestProcesses(t *testing.T) {
+	mapping := make(chan struct{})
+	proceed := make(chan struct{})
+
+	process := meduce.NewDefaultProcess(meduce.Config[int, int, int, int]{
+		Name: "counting",
+		Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+			if value == 0 {
+				close(mapping)
+				<-proceed
+			}
+
+			emit(value%10, 1)
+		},
+		Reducer:    sum,
+		Source:     sources.NewSliceSource(numbers(100)),
+		Collector:  collectors.NewMapCollector[int, int](),
+		MapWorkers: 1,
+	})
+
+	failing := meduce.NewDefaultProcess(meduce.Config[int, int, int, int]{
+		Name:      "failing",
+		Reducer:   sum,
+		Source:    sources.NewSliceSource(numbers(100)),
+		Collector: collectors.NewMapCollector[int, int](),
+	})
+
+	if process.Uid() == failing.Uid() {
+		t.Errorf("processes have the same uid %d", process.Uid())
+	}
+
+	go process.Run()
+	waitFor(t, mapping, "process was not started")
+
+	info := processInfo(t, process.Uid())
+	if info.Name != "counting" || info.Status != meduce.StatusRunning || !info.Finished.IsZero() {
+		t.Errorf("running process is described as %+v", info)
+	}
+
+	close(proceed)
+	if _, err := process.WaitToFinish(); err != nil {
+		t.Fatalf("WaitToFinish() = %v", err)
+	}
+
+	info = processInfo(t, process.Uid())
+	if info.Status != meduce.StatusSucceeded || info.Err != nil || info.Finished.Before(info.Started) {
+		t.Errorf("finished process is described as %+v", info)
+	}
+
+	err := failing.Run()
+
+	info = processInfo(t, failing.Uid())
+	if info.Status != meduce.StatusFailed || !errors.Is(info.Err, meduce.ErrNoMapper) || info.Err != err {
+		t.Errorf("failed process is described as %+v, want error %v", info, err)
+	}
+}
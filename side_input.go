@@ -0,0 +1,175 @@
+package meduce
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A SideInputLoader is a side input that can be
+// materialized before a process starts mapping.
+//
+// It is implemented by SideInput.
+type SideInputLoader interface {
+	Name() string
+	load(ctx context.Context) error
+}
+
+// A SideInput is a small dataset that is fully read into memory
+// before mapping starts, so that every mapper can look up its values.
+//
+// Mappers capture the side input and read it after it is loaded.
+// It is loaded only once, even if it is used by multiple processes.
+type SideInput[Key comparable, Value any] struct {
+	name string
+	read func(ctx context.Context, data map[Key]Value) error
+
+	loading sync.Mutex
+	data    atomic.Pointer[map[Key]Value]
+}
+
+// NewSideInput creates a side input that reads all pairs of the source.
+// If a key appears multiple times, its last value is kept.
+// If the source fails, loading of the side input fails with its error.
+func NewSideInput[Key comparable, Value any](name string, source Source[Key, Value]) *SideInput[Key, Value] {
+	return &SideInput[Key, Value]{
+		name: name,
+		read: func(ctx context.Context, data map[Key]Value) error {
+			for {
+				select {
+				case <-ctx.Done():
+					source.stop()
+					return context.Cause(ctx)
+				case pair, ok := <-source.records:
+					if !ok {
+						return source.Err()
+					}
+
+					data[pair.First] = pair.Second
+				}
+			}
+		},
+	}
+}
+
+// NewProcessSideInput creates a side input that runs the process
+// and collects its output. Collector of the process is replaced.
+func NewProcessSideInput[KeyIn, ValueIn any, Key comparable, ValueMid, Value any](
+	name string,
+	process *AggregationProcess[KeyIn, ValueIn, Key, ValueMid, Value],
+) *SideInput[Key, Value] {
+	collector := make(sideInputCollector[Key, Value])
+	process.Collector = collector
+
+	return &SideInput[Key, Value]{
+		name: name,
+		read: func(ctx context.Context, data map[Key]Value) error {
+			if err := process.RunContext(ctx); err != nil {
+				return err
+			}
+
+			for key, value := range collector {
+				data[key] = value
+			}
+
+			return nil
+		},
+	}
+}
+
+// Name returns the name of the side input.
+func (input *SideInput[Key, Value]) Name() string {
+	return input.name
+}
+
+// Get returns the value of the key, and whether it exists.
+func (input *SideInput[Key, Value]) Get(key Key) (Value, bool) {
+	value, ok := input.Map()[key]
+	return value, ok
+}
+
+// Map returns all pairs of the side input, or nil if it is not loaded yet.
+// It must not be modified.
+func (input *SideInput[Key, Value]) Map() map[Key]Value {
+	data := input.data.Load()
+	if data == nil {
+		return nil
+	}
+
+	return *data
+}
+
+func (input *SideInput[Key, Value]) load(ctx context.Context) error {
+	input.loading.Lock()
+	defer input.loading.Unlock()
+
+	if input.data.Load() != nil {
+		return nil
+	}
+
+	data := make(map[Key]Value)
+	if err := input.read(ctx, data); err != nil {
+		return fmt.Errorf("meduce: side input %s: %w", input.name, err)
+	}
+
+	input.data.Store(&data)
+
+	return nil
+}
+
+type sideInputCollector[Key comparable, Value any] map[Key]Value
+
+func (collector sideInputCollector[Key, Value]) Init() error {
+	return nil
+}
+
+func (collector sideInputCollector[Key, Value]) Collect(key Key, value Value) error {
+	collector[key] = value
+	return nil
+}
+
+func (collector sideInputCollector[Key, Value]) Finalize() error {
+	return nil
+}
+
+// loadSideInputs loads all side inputs of the process at once,
+// and returns the first error that happened.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) loadSideInputs(ctx context.Context) error {
+	if len(process.SideInputs) == 0 {
+		return nil
+	}
+
+	loadStart := time.Now()
+
+	errs := make([]error, len(process.SideInputs))
+
+	var allLoaded sync.WaitGroup
+	allLoaded.Add(len(process.SideInputs))
+	for i, input := range process.SideInputs {
+		go func() {
+			defer allLoaded.Done()
+			errs[i] = input.load(ctx)
+		}()
+	}
+	allLoaded.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	if process.Logger != nil {
+		process.Logger.Printf("Process %s: %d side inputs loaded\n", process.label(), len(process.SideInputs))
+	}
+	process.logEvent(
+		process.LogLevels.process(), LogSideInputsLoaded,
+		slog.Int("side_inputs", len(process.SideInputs)),
+		slog.Duration("duration", time.Since(loadStart)),
+	)
+
+	return nil
+}
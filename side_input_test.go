@@ -0,0 +1,98 @@
+package meduce_test
+
+import (
+	"errors"
+	"github.com/djordje200179/meduce"
+	"github.com/djordje200179/meduce/collectors"
+	"github.com/djordje200179/meduce/sources"
+	"sync/atomic"
+	"testing"
+)
+
+// parityConfig creates a configuration of a process
+// that counts numbers by names of their parities.
+func parityConfig(parities *meduce.SideInput[int, string]) meduce.Config[int, int, string, int] {
+	return meduce.Config[int, int, string, int]{
+		Mapper: func(_ int, value int, emit meduce.Emitter[string, int]) {
+			name, _ := parities.Get(value % 2)
+			emit(name, 1)
+		},
+		Reducer: func(_ string, values []int) int {
+			return sum(0, values)
+		},
+		SideInputs: []meduce.SideInputLoader{parities},
+		Source:     sources.NewSliceSource(numbers(100)),
+	}
+}
+
+func TestSideInput(t *testing.T) {
+	var reads atomic.Int32
+	source := meduce.NewSource(func(writer meduce.SourceWriter[int, string]) error {
+		reads.Add(1)
+		writer.Write(0, "even")
+		writer.Write(1, "odd")
+		return nil
+	})
+
+	parities := meduce.NewSideInput("parities", source)
+	if parities.Map() != nil {
+		t.Error("side input was loaded before a process was run")
+	}
+
+	for range 2 {
+		collector := collectors.NewMapCollector[string, int]()
+
+		config := parityConfig(parities)
+		config.Collector = collector
+		if err := meduce.NewDefaultProcess(config).Run(); err != nil {
+			t.Fatalf("Run() = %v", err)
+		}
+
+		if collector["even"] != 50 || collector["odd"] != 50 {
+			t.Errorf("collected %v, want 50 even and 50 odd numbers", collector)
+		}
+	}
+
+	if reads.Load() != 1 {
+		t.Errorf("side input was read %d times, want once", reads.Load())
+	}
+}
+
+func TestProcessSideInput(t *testing.T) {
+	naming := meduce.NewDefaultProcess(meduce.Config[int, string, int, string]{
+		Mapper: func(key int, value string, emit meduce.Emitter[int, string]) {
+			emit(key, value)
+		},
+		Reducer: func(_ int, values []string) string {
+			return values[0]
+		},
+		Source: sources.NewSliceSource([]string{"even", "odd"}),
+	})
+
+	collector := collectors.NewMapCollector[string, int]()
+
+	config := parityConfig(meduce.NewProcessSideInput("parities", naming))
+	config.Collector = collector
+	if err := meduce.NewDefaultProcess(config).Run(); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+
+	if collector["even"] != 50 || collector["odd"] != 50 {
+		t.Errorf("collected %v, want 50 even and 50 odd numbers", collector)
+	}
+}
+
+func TestSideInputError(t *testing.T) {
+	errRead := errors.New("read failed")
+	source := meduce.NewSource(func(writer meduce.SourceWriter[int, string]) error {
+		writer.Write(0, "even")
+		return errRead
+	})
+
+	config := parityConfig(meduce.NewSideInput("parities", source))
+	config.Collector = collectors.NewMapCollector[string, int]()
+
+	if err := meduce.NewDefaultProcess(config).Run(); !errors.Is(err, errRead) {
+		t.Errorf("Run() = %v, want %v", err, errRead)
+	}
+}
@@ -0,0 +1,286 @@
+package meduce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/djordje200179/extendedlibrary/misc"
+	"sync"
+	"sync/atomic"
+)
+
+// sourceBufferSize is the size of the buffer of sources created by NewSource.
+const sourceBufferSize = 100
+
+// A Source is a stream of key-value pairs from which a process reads its input.
+//
+// It is created from a channel with NewChannelSource, or with NewSource
+// from a function that produces its records and can report an error.
+// Zero value of Source has no records.
+type Source[KeyIn, ValueIn any] struct {
+	records <-chan misc.Pair[KeyIn, ValueIn]
+	state   *sourceState
+}
+
+// NewChannelSource creates a source that reads key-value
+// pairs from the channel, until the channel is closed.
+//
+// Preferably, the channel should be buffered to avoid
+// blocking and context switches.
+func NewChannelSource[KeyIn, ValueIn any](records <-chan misc.Pair[KeyIn, ValueIn]) Source[KeyIn, ValueIn] {
+	return Source[KeyIn, ValueIn]{records: records}
+}
+
+// Records returns the channel from which records of the source are read.
+func (source Source[KeyIn, ValueIn]) Records() <-chan misc.Pair[KeyIn, ValueIn] {
+	return source.records
+}
+
+// Err returns the error that stopped the source,
+// or nil if the source is not finished yet or finished successfully.
+// Sources created from channels never fail.
+func (source Source[KeyIn, ValueIn]) Err() error {
+	return source.state.Err()
+}
+
+// A SourceWriter passes records of a source created
+// by NewSource to the process that reads the source.
+type SourceWriter[KeyIn, ValueIn any] struct {
+	ctx     context.Context
+	records chan<- misc.Pair[KeyIn, ValueIn]
+	state   *sourceState
+}
+
+// Context returns a context that is cancelled when
+// the process that reads the source stops reading it.
+func (writer SourceWriter[KeyIn, ValueIn]) Context() context.Context {
+	return writer.ctx
+}
+
+// Write passes a record to the process. It reports false
+// if the source should stop, as the process stopped reading it.
+func (writer SourceWriter[KeyIn, ValueIn]) Write(key KeyIn, value ValueIn) bool {
+	select {
+	case writer.records <- misc.Pair[KeyIn, ValueIn]{key, value}:
+		return true
+	case <-writer.ctx.Done():
+		return false
+	}
+}
+
+// SetSize sets the number of bytes that the source reads (like
+// size of the file that is read), so that progress can be reported.
+// It should be called before the first record is written.
+func (writer SourceWriter[KeyIn, ValueIn]) SetSize(size int64) {
+	writer.state.size.Store(size)
+}
+
+// AddBytesRead adds to the number of bytes that the source read.
+// Once it reads all of them, it should equal the size of the source.
+func (writer SourceWriter[KeyIn, ValueIn]) AddBytesRead(bytes int64) {
+	writer.state.bytesRead.Add(bytes)
+}
+
+// A sourceState is the state of a source created by NewSource.
+type sourceState struct {
+	stop context.CancelFunc
+	done chan struct{}
+	err  error
+
+	size      atomic.Int64
+	bytesRead atomic.Int64
+	// sources are states of sources whose records are forwarded by the source.
+	sources []*sourceState
+}
+
+// NewSource creates a source whose records are produced by the given function,
+// which is called in a new goroutine. Records are passed with the writer,
+// and the source is closed when the function returns.
+//
+// If the function returns an error, the process that reads
+// the source is stopped, and the error is returned from Run.
+func NewSource[KeyIn, ValueIn any](produce func(writer SourceWriter[KeyIn, ValueIn]) error) Source[KeyIn, ValueIn] {
+	return newSource(produce, nil)
+}
+
+// newSource creates a source like NewSource, which forwards
+// records of sources with the given states.
+func newSource[KeyIn, ValueIn any](
+	produce func(writer SourceWriter[KeyIn, ValueIn]) error,
+	sources []*sourceState,
+) Source[KeyIn, ValueIn] {
+	records := make(chan misc.Pair[KeyIn, ValueIn], sourceBufferSize)
+
+	ctx, stop := context.WithCancel(context.Background())
+	state := &sourceState{stop: stop, done: make(chan struct{}), sources: sources}
+
+	go func() {
+		defer stop()
+
+		state.err = produceRecords(produce, SourceWriter[KeyIn, ValueIn]{ctx, records, state})
+
+		// State is done before records are closed, so that
+		// the error is known once all records were read.
+		close(state.done)
+		close(records)
+	}()
+
+	return Source[KeyIn, ValueIn]{records, state}
+}
+
+// produceRecords calls the function that produces records of a source,
+// and returns a panic in it as an error.
+func produceRecords[KeyIn, ValueIn any](
+	produce func(writer SourceWriter[KeyIn, ValueIn]) error,
+	writer SourceWriter[KeyIn, ValueIn],
+) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			err = fmt.Errorf("panic: %v", value)
+		}
+	}()
+
+	return produce(writer)
+}
+
+// Err returns the error that stopped the source,
+// or nil if the source is not finished yet.
+func (state *sourceState) Err() error {
+	if state == nil {
+		return nil
+	}
+
+	select {
+	case <-state.done:
+	default:
+		return nil
+	}
+
+	if state.err == nil {
+		return nil
+	}
+
+	return fmt.Errorf("meduce: reading source: %w", state.err)
+}
+
+// Size returns the number of bytes that the source reads,
+// or 0 if it is not known. Size of a source that forwards
+// other sources is known if sizes of all of them are.
+func (state *sourceState) Size() int64 {
+	if state == nil {
+		return 0
+	} else if size := state.size.Load(); size > 0 || len(state.sources) == 0 {
+		return size
+	}
+
+	var size int64
+	for _, source := range state.sources {
+		sourceSize := source.Size()
+		if sourceSize == 0 {
+			return 0
+		}
+
+		size += sourceSize
+	}
+
+	return size
+}
+
+// BytesRead returns the number of bytes that the source
+// and sources forwarded by it reported as read.
+func (state *sourceState) BytesRead() int64 {
+	if state == nil {
+		return 0
+	}
+
+	bytesRead := state.bytesRead.Load()
+	for _, source := range state.sources {
+		bytesRead += source.BytesRead()
+	}
+
+	return bytesRead
+}
+
+// reportsBytes reports whether the source reported its size or bytes read,
+// so that lengths of its records don't have to be measured.
+func (state *sourceState) reportsBytes() bool {
+	return state.Size() > 0 || state.BytesRead() > 0
+}
+
+// MergeSources creates a source that reads records of all given sources,
+// in the order in which they arrive. Errors of the given sources
+// are errors of the created source.
+func MergeSources[KeyIn, ValueIn any](sources ...Source[KeyIn, ValueIn]) Source[KeyIn, ValueIn] {
+	states := make([]*sourceState, len(sources))
+	for i, source := range sources {
+		states[i] = source.state
+	}
+
+	return newSource(func(writer SourceWriter[KeyIn, ValueIn]) error {
+		var sourcesFinished sync.WaitGroup
+		sourcesFinished.Add(len(sources))
+
+		for _, source := range sources {
+			go func(source Source[KeyIn, ValueIn]) {
+				defer sourcesFinished.Done()
+
+				if !forwardSource(writer.Context(), source, func(pair misc.Pair[KeyIn, ValueIn]) bool {
+					return writer.Write(pair.First, pair.Second)
+				}) {
+					source.stop()
+				}
+			}(source)
+		}
+
+		sourcesFinished.Wait()
+
+		errs := make([]error, len(sources))
+		for i, source := range sources {
+			errs[i] = source.Err()
+		}
+
+		return errors.Join(errs...)
+	}, states)
+}
+
+// forwardSource passes all records of the source to the write function,
+// until the context is cancelled or the function reports false.
+// It reports whether all records were passed.
+func forwardSource[KeyIn, ValueIn any](
+	ctx context.Context,
+	source Source[KeyIn, ValueIn],
+	write func(pair misc.Pair[KeyIn, ValueIn]) bool,
+) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case pair, ok := <-source.records:
+			if !ok {
+				return true
+			}
+
+			if !write(pair) {
+				return false
+			}
+		}
+	}
+}
+
+// stop stops the source when its records won't be read anymore.
+// Sources created by NewSource are cancelled, and the rest of records
+// is drained, so that the producer of the source is not blocked forever.
+func (source Source[KeyIn, ValueIn]) stop() {
+	if source.state != nil {
+		source.state.stop()
+	}
+
+	if source.records != nil {
+		go drainSource(source.records)
+	}
+}
+
+func drainSource[KeyIn, ValueIn any](records <-chan misc.Pair[KeyIn, ValueIn]) {
+	for range records {
+	}
+}
@@ -2,37 +2,53 @@ package sources
 
 import (
 	"bufio"
-	"github.com/djordje200179/extendedlibrary/misc"
 	"github.com/djordje200179/meduce"
 	"os"
 )
 
+// MaxLineLength is the maximal length of a line that file sources read.
+// A longer line stops the source with bufio.ErrTooLong.
+const MaxLineLength = 64 * 1024 * 1024
+
 // NewFileSource creates a new source that reads a file
-// from the given path line by line.
-func NewFileSource(path string) meduce.Source[int, string] {
+// from the given path line by line. It reports size of
+// the file and bytes read, so progress of processes can be reported.
+//
+// An error is returned if the file can't be opened.
+// Errors that happen while the file is read are
+// returned from Run of the process that reads the source.
+func NewFileSource(path string) (meduce.Source[int, string], error) {
 	file, err := os.Open(path)
 	if err != nil {
-		panic(err)
+		return meduce.Source[int, string]{}, err
 	}
 
-	scanner := bufio.NewScanner(file)
-	scanner.Split(bufio.ScanLines)
+	source := meduce.NewSource(func(writer meduce.SourceWriter[int, string]) error {
+		defer file.Close()
+
+		if info, err := file.Stat(); err == nil {
+			writer.SetSize(info.Size())
+		}
 
-	source := make(chan misc.Pair[int, string], 100)
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), MaxLineLength)
+		scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+			advance, token, err := bufio.ScanLines(data, atEOF)
+			// Lines are counted with their terminators, so that all bytes are read at the end.
+			writer.AddBytesRead(int64(advance))
+			return advance, token, err
+		})
 
-	go func() {
 		lineIndex := 0
 		for scanner.Scan() {
-			source <- misc.Pair[int, string]{lineIndex, scanner.Text()}
+			if !writer.Write(lineIndex, scanner.Text()) {
+				return nil
+			}
 			lineIndex++
 		}
-		close(source)
 
-		err := file.Close()
-		if err != nil {
-			panic(err)
-		}
-	}()
+		return scanner.Err()
+	})
 
-	return source
+	return source, nil
 }
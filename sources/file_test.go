@@ -0,0 +1,150 @@
+package sources_test
+
+import (
+	"bufio"
+	"errors"
+	"github.com/djordje200179/meduce"
+	"github.com/djordje200179/meduce/collectors"
+	"github.com/djordje200179/meduce/sources"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// lineLengths runs a process that collects lengths of lines of the source by their indexes.
+func lineLengths(source meduce.Source[int, string]) (map[int]int, error) {
+	collector := collectors.NewMapCollector[int, int]()
+
+	process := meduce.NewDefaultProcess(meduce.Config[int, string, int, int]{
+		Mapper: func(index int, line string, emit meduce.Emitter[int, int]) {
+			emit(index, len(line))
+		},
+		Reducer: func(_ int, values []int) int {
+			return values[0]
+		},
+		Source:    source,
+		Collector: collector,
+	})
+
+	err := process.Run()
+	return collector, err
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "input.txt")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestFileSource(t *testing.T) {
+	path := writeFile(t, "a\r\nbb\n\nlong "+strings.Repeat("x", 100_000)+"\nlast")
+
+	source, err := sources.NewFileSource(path)
+	if err != nil {
+		t.Fatalf("NewFileSource() = %v", err)
+	}
+
+	lengths, err := lineLengths(source)
+	if err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+
+	expected := map[int]int{0: 1, 1: 2, 2: 0, 3: 100_005, 4: 4}
+	if len(lengths) != len(expected) {
+		t.Fatalf("Run() collected %v, want %v", lengths, expected)
+	}
+
+	for index, length := range expected {
+		if lengths[index] != length {
+			t.Errorf("line %d has length %d, want %d", index, lengths[index], length)
+		}
+	}
+}
+
+func TestFileSourceMissing(t *testing.T) {
+	if _, err := sources.NewFileSource(filepath.Join(t.TempDir(), "missing.txt")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("NewFileSource() = %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestFileSourceTooLongLine(t *testing.T) {
+	path := writeFile(t, "a\n"+strings.Repeat("x", sources.MaxLineLength+1))
+
+	source, err := sources.NewFileSource(path)
+	if err != nil {
+		t.Fatalf("NewFileSource() = %v", err)
+	}
+
+	if _, err := lineLengths(source); !errors.Is(err, bufio.ErrTooLong) {
+		t.Errorf("Run() = %v, want %v", err, bufio.ErrTooLong)
+	}
+}
+
+func TestFileSourceReadError(t *testing.T) {
+	source, err := sources.NewFileSource(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileSource() = %v", err)
+	}
+
+	if _, err := lineLengths(source); err == nil {
+		t.Error("Run() = nil, want an error reading a directory")
+	}
+}
+
+func TestAggregateDataSourcesError(t *testing.T) {
+	first, _ := sources.NewFileSource(writeFile(t, "a\nbb\n"))
+	second, _ := sources.NewFileSource(t.TempDir())
+
+	if _, err := lineLengths(sources.AggregateDataSources(first, second)); err == nil {
+		t.Error("Run() = nil, want an error of the second source")
+	}
+}
+
+func TestFileSourceProgress(t *testing.T) {
+	content := "first\r\nsecond\n\nlast"
+	path := writeFile(t, content)
+
+	source, err := sources.NewFileSource(path)
+	if err != nil {
+		t.Fatalf("NewFileSource() = %v", err)
+	}
+
+	process := meduce.NewDefaultProcess(meduce.Config[int, string, int, int]{
+		Mapper: func(index int, line string, emit meduce.Emitter[int, int]) {
+			emit(index, len(line))
+		},
+		Reducer: func(_ int, values []int) int {
+			return values[0]
+		},
+		Source:    source,
+		Collector: collectors.NewMapCollector[int, int](),
+	})
+
+	if err := process.Run(); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+
+	if progress := process.Progress(); progress.SourceSize != int64(len(content)) || progress.BytesRead != int64(len(content)) {
+		t.Errorf("Progress() = %+v, want all %d bytes read", progress, len(content))
+	}
+
+	// Line terminators are counted too, unlike when lengths of lines are measured.
+	stats := process.Stats()
+	if stats.BytesRead != int64(len(content)) {
+		t.Errorf("Stats().BytesRead = %d, want %d", stats.BytesRead, len(content))
+	}
+
+	// Lines are not measured by mapping threads, as the source reports bytes read.
+	for i, threadStats := range stats.MappingThreads {
+		if threadStats.BytesRead != 0 {
+			t.Errorf("mapping thread %d measured %d bytes, want 0", i, threadStats.BytesRead)
+		}
+	}
+}
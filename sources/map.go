@@ -1,21 +1,17 @@
 package sources
 
-import (
-	"github.com/djordje200179/extendedlibrary/misc"
-	"github.com/djordje200179/meduce"
-)
+import "github.com/djordje200179/meduce"
 
 // NewMapSource creates a new source that iterates over
 // the given map and returns its key-value pairs.
 func NewMapSource[K comparable, V any](m map[K]V) meduce.Source[K, V] {
-	source := make(chan misc.Pair[K, V], 100)
-
-	go func() {
+	return meduce.NewSource(func(writer meduce.SourceWriter[K, V]) error {
 		for key, value := range m {
-			source <- misc.Pair[K, V]{key, value}
+			if !writer.Write(key, value) {
+				break
+			}
 		}
-		close(source)
-	}()
 
-	return source
+		return nil
+	})
 }
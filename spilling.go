@@ -0,0 +1,421 @@
+package meduce
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"unsafe"
+)
+
+// maxOpenRuns is the maximal number of spilled runs that are merged at once,
+// so that the number of open files stays bounded.
+const maxOpenRuns = 64
+
+// memoryLimit returns how many bytes emitted key-value pairs of a single
+// mapping thread can occupy before they are spilled to a temporary file.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) memoryLimit(threadsCount int) int {
+	if process.MemoryLimit <= 0 {
+		return 0
+	}
+
+	limit := process.MemoryLimit / threadsCount
+	if limit < 1 {
+		return 1
+	}
+
+	return limit
+}
+
+// pairSizer returns a function that measures how many bytes an emitted key-value pair occupies.
+// If PairSize is not set, contents of strings and byte slices
+// are counted together with sizes of key and value types.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) pairSizer() func(key KeyOut, value ValueMid) int {
+	if process.PairSize != nil {
+		return process.PairSize
+	}
+
+	var key KeyOut
+	var value ValueMid
+	typesSize := int(unsafe.Sizeof(key) + unsafe.Sizeof(value))
+
+	measureKeys, measureValues := hasLength[KeyOut](), hasLength[ValueMid]()
+
+	return func(key KeyOut, value ValueMid) int {
+		size := typesSize
+		if measureKeys {
+			size += byteLength(key)
+		}
+		if measureValues {
+			size += byteLength(value)
+		}
+
+		return size
+	}
+}
+
+// hasLength reports whether values of type T are strings or byte slices,
+// so that contents of other types are not measured.
+func hasLength[T any]() bool {
+	var value T
+
+	switch any(value).(type) {
+	case string, []byte:
+		return true
+	default:
+		return false
+	}
+}
+
+// byteLength returns the length of a string or a byte slice,
+// or 0 if the value is neither.
+func byteLength[T any](value T) int {
+	switch value := any(value).(type) {
+	case string:
+		return len(value)
+	case []byte:
+		return len(value)
+	default:
+		return 0
+	}
+}
+
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) keyCodec() Codec[KeyOut] {
+	if process.KeyCodec != nil {
+		return process.KeyCodec
+	}
+
+	return GobCodec[KeyOut]{}
+}
+
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) valueCodec() Codec[ValueMid] {
+	if process.ValueCodec != nil {
+		return process.ValueCodec
+	}
+
+	return GobCodec[ValueMid]{}
+}
+
+// spilled reports whether any of the mapping threads
+// wrote its data to temporary files.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) spilled() bool {
+	for _, thread := range process.mappingThreads {
+		if len(thread.runs) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// releaseMappedData removes temporary files of all mapping threads
+// and releases data that they kept in memory.
+// Runs in CheckpointDir are kept, as they are removed with the checkpoint.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) releaseMappedData() {
+	for _, path := range process.intermediateRuns {
+		_ = os.Remove(path)
+	}
+	process.intermediateRuns = nil
+
+	for i := range process.mappingThreads {
+		if process.CheckpointDir == "" {
+			for _, path := range process.mappingThreads[i].runs {
+				_ = os.Remove(path)
+			}
+		}
+
+		process.mappingThreads[i].runs = nil
+		process.mappingThreads[i].table = nil
+		process.mappingThreads[i].keys = nil
+		process.mappingThreads[i].values = nil
+	}
+}
+
+// spill sorts and combines data of the mapping thread
+// and writes it to a new temporary file, or to a run in CheckpointDir.
+func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) spill() error {
+	thread.sortAndCombine()
+
+	dir := thread.SpillDir
+	if thread.CheckpointDir != "" {
+		dir = thread.CheckpointDir
+	}
+
+	index := 0
+	path, err := writeRun(dir, thread.keyCodec(), thread.valueCodec(), func() (key KeyOut, value ValueMid, ok bool) {
+		if index == len(thread.keys) {
+			return key, value, false
+		}
+
+		index++
+		return thread.keys[index-1], thread.values[index-1], true
+	})
+	if err != nil {
+		return err
+	}
+	thread.runs = append(thread.runs, path)
+
+	thread.spilledCount += len(thread.keys)
+	thread.keys = nil
+	thread.values = nil
+	thread.bufferedSize = 0
+
+	return nil
+}
+
+// writeRun writes sorted key-value pairs returned by next
+// to a new file in the directory and returns its path.
+// The file is removed if the pairs can't be written.
+func writeRun[KeyOut, ValueMid any](
+	dir string,
+	keyCodec Codec[KeyOut], valueCodec Codec[ValueMid],
+	next func() (KeyOut, ValueMid, bool),
+) (string, error) {
+	file, err := os.CreateTemp(dir, runFilesPattern)
+	if err != nil {
+		return "", err
+	}
+
+	writer := bufio.NewWriter(file)
+	keyEncoder := keyCodec.NewEncoder(writer)
+	valueEncoder := valueCodec.NewEncoder(writer)
+
+	for key, value, ok := next(); ok; key, value, ok = next() {
+		if err = keyEncoder.Encode(key); err != nil {
+			break
+		}
+
+		if err = valueEncoder.Encode(value); err != nil {
+			break
+		}
+	}
+
+	if err == nil {
+		err = writer.Flush()
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return "", err
+	}
+
+	return file.Name(), nil
+}
+
+// A runReader reads sorted key-value pairs of a single run.
+type runReader[KeyOut, ValueMid any] interface {
+	next() bool // next advances to the next pair and reports whether it exists
+	current() (KeyOut, ValueMid)
+	err() error
+	close() error
+}
+
+type memoryRunReader[KeyOut, ValueMid any] struct {
+	keys   []KeyOut
+	values []ValueMid
+	index  int
+}
+
+func (reader *memoryRunReader[KeyOut, ValueMid]) next() bool {
+	reader.index++
+	return reader.index < len(reader.keys)
+}
+
+func (reader *memoryRunReader[KeyOut, ValueMid]) current() (KeyOut, ValueMid) {
+	return reader.keys[reader.index], reader.values[reader.index]
+}
+
+func (reader *memoryRunReader[KeyOut, ValueMid]) err() error {
+	return nil
+}
+
+func (reader *memoryRunReader[KeyOut, ValueMid]) close() error {
+	return nil
+}
+
+type fileRunReader[KeyOut, ValueMid any] struct {
+	file         *os.File
+	keyDecoder   Decoder[KeyOut]
+	valueDecoder Decoder[ValueMid]
+
+	key   KeyOut
+	value ValueMid
+	error error
+}
+
+func openFileRun[KeyOut, ValueMid any](
+	path string,
+	keyCodec Codec[KeyOut], valueCodec Codec[ValueMid],
+) (*fileRunReader[KeyOut, ValueMid], error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(file)
+
+	return &fileRunReader[KeyOut, ValueMid]{
+		file:         file,
+		keyDecoder:   keyCodec.NewDecoder(reader),
+		valueDecoder: valueCodec.NewDecoder(reader),
+	}, nil
+}
+
+func (reader *fileRunReader[KeyOut, ValueMid]) next() bool {
+	if reader.error != nil {
+		return false
+	}
+
+	key, err := reader.keyDecoder.Decode()
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			reader.error = err
+		}
+		return false
+	}
+
+	value, err := reader.valueDecoder.Decode()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		reader.error = err
+		return false
+	}
+
+	reader.key, reader.value = key, value
+	return true
+}
+
+func (reader *fileRunReader[KeyOut, ValueMid]) current() (KeyOut, ValueMid) {
+	return reader.key, reader.value
+}
+
+func (reader *fileRunReader[KeyOut, ValueMid]) err() error {
+	return reader.error
+}
+
+func (reader *fileRunReader[KeyOut, ValueMid]) close() error {
+	return reader.file.Close()
+}
+
+// openRuns opens readers for all runs of all mapping threads,
+// both the spilled ones and the ones that stayed in memory.
+// If there are more than maxOpenRuns spilled runs, they are merged in passes first.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) openRuns(ctx context.Context) ([]runReader[KeyOut, ValueMid], error) {
+	var paths []string
+	for _, thread := range process.mappingThreads {
+		paths = append(paths, thread.runs...)
+	}
+
+	paths, err := process.mergeRuns(ctx, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	readers, err := openFileRuns(paths, process.keyCodec(), process.valueCodec())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, thread := range process.mappingThreads {
+		if len(thread.keys) > 0 {
+			readers = append(readers, &memoryRunReader[KeyOut, ValueMid]{
+				keys:   thread.keys,
+				values: thread.values,
+				index:  -1,
+			})
+		}
+	}
+
+	return readers, nil
+}
+
+func openFileRuns[KeyOut, ValueMid any](
+	paths []string,
+	keyCodec Codec[KeyOut], valueCodec Codec[ValueMid],
+) ([]runReader[KeyOut, ValueMid], error) {
+	readers := make([]runReader[KeyOut, ValueMid], 0, len(paths))
+
+	for _, path := range paths {
+		reader, err := openFileRun(path, keyCodec, valueCodec)
+		if err != nil {
+			closeRuns(readers)
+			return nil, err
+		}
+
+		readers = append(readers, reader)
+	}
+
+	return readers, nil
+}
+
+// mergeRuns merges spilled runs into intermediate runs in SpillDir,
+// maxOpenRuns of them at once, until at most maxOpenRuns runs are left.
+// It returns paths of the runs that are left.
+//
+// Intermediate runs are removed as soon as they are merged again,
+// and the rest of them together with spilled runs.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) mergeRuns(ctx context.Context, paths []string) ([]string, error) {
+	for len(paths) > maxOpenRuns {
+		if ctx.Err() != nil {
+			return nil, context.Cause(ctx)
+		}
+
+		merged := paths[:maxOpenRuns]
+
+		path, err := process.mergeRunFiles(process.SpillDir, merged)
+		if err != nil {
+			return nil, err
+		}
+
+		process.intermediateRuns = slices.DeleteFunc(process.intermediateRuns, func(intermediatePath string) bool {
+			if !slices.Contains(merged, intermediatePath) {
+				return false
+			}
+
+			_ = os.Remove(intermediatePath)
+			return true
+		})
+		process.intermediateRuns = append(process.intermediateRuns, path)
+
+		paths = append(slices.Clone(paths[maxOpenRuns:]), path)
+	}
+
+	return paths, nil
+}
+
+// mergeRunFiles merges spilled runs into a single new run in the directory and returns its path.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) mergeRunFiles(dir string, paths []string) (string, error) {
+	readers, err := openFileRuns(paths, process.keyCodec(), process.valueCodec())
+	if err != nil {
+		return "", err
+	}
+	defer closeRuns(readers)
+
+	merger := newRunsMerger(process.KeyComparator, process.ValueComparator, readers)
+
+	path, err := writeRun(dir, process.keyCodec(), process.valueCodec(), merger.next)
+	if err != nil {
+		return "", err
+	}
+
+	if err := merger.err(); err != nil {
+		_ = os.Remove(path)
+		return "", err
+	}
+
+	return path, nil
+}
+
+func closeRuns[KeyOut, ValueMid any](readers []runReader[KeyOut, ValueMid]) {
+	for _, reader := range readers {
+		_ = reader.close()
+	}
+}
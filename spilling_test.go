@@ -0,0 +1,154 @@
+package meduce_test
+
+import (
+	"errors"
+	"github.com/djordje200179/meduce"
+	"github.com/djordje200179/meduce/collectors"
+	"github.com/djordje200179/meduce/sources"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// countingCodec is a GobCodec that counts written runs.
+type countingCodec[T any] struct {
+	meduce.GobCodec[T]
+
+	runs *atomic.Int32
+}
+
+func (codec countingCodec[T]) NewEncoder(writer io.Writer) meduce.Encoder[T] {
+	codec.runs.Add(1)
+	return codec.GobCodec.NewEncoder(writer)
+}
+
+// failingEncoder fails to encode any value.
+type failingEncoder[T any] struct{}
+
+var errEncode = errors.New("encoding failed")
+
+func (failingEncoder[T]) Encode(T) error {
+	return errEncode
+}
+
+type failingCodec[T any] struct {
+	meduce.GobCodec[T]
+}
+
+func (failingCodec[T]) NewEncoder(io.Writer) meduce.Encoder[T] {
+	return failingEncoder[T]{}
+}
+
+// assertEmptyDir checks that all temporary files were removed from the directory.
+func assertEmptyDir(t *testing.T, dir string) {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) > 0 {
+		t.Errorf("%d temporary files were not removed", len(entries))
+	}
+}
+
+func TestMemoryLimit(t *testing.T) {
+	var runs atomic.Int32
+	collector := collectors.NewMapCollector[int, int]()
+	spillDir := t.TempDir()
+
+	process := meduce.NewDefaultProcess(meduce.Config[int, int, int, int]{
+		Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+			emit(value%100, 1)
+		},
+		Reducer:     sum,
+		Source:      sources.NewSliceSource(numbers(7000)),
+		Collector:   collector,
+		MapWorkers:  1,
+		MemoryLimit: 100,
+		PairSize:    func(int, int) int { return 1 },
+		SpillDir:    spillDir,
+		KeyCodec:    countingCodec[int]{runs: &runs},
+	})
+
+	if err := process.Run(); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+
+	if len(collector) != 100 {
+		t.Fatalf("Run() collected %d keys, want 100", len(collector))
+	}
+
+	for key, count := range collector {
+		if count != 70 {
+			t.Errorf("key %d was counted %d times, want 70", key, count)
+		}
+	}
+
+	// 70 spilled runs are more than can be opened at once,
+	// so the first 64 of them are merged into an intermediate run.
+	if runs := runs.Load(); runs != 71 {
+		t.Errorf("%d runs were written, want 71", runs)
+	}
+
+	assertEmptyDir(t, spillDir)
+}
+
+func TestMemoryLimitStrings(t *testing.T) {
+	var runs atomic.Int32
+
+	lines := make([]string, 1000)
+	for i := range lines {
+		lines[i] = string(rune('a'+i%26)) + strings.Repeat(" ", 1000)
+	}
+
+	process := meduce.NewDefaultProcess(meduce.Config[int, string, string, int]{
+		Mapper: func(_ int, line string, emit meduce.Emitter[string, int]) {
+			emit(line, 1)
+		},
+		Reducer: func(_ string, values []int) int {
+			return len(values)
+		},
+		Source:      sources.NewSliceSource(lines),
+		Collector:   collectors.NewMapCollector[string, int](),
+		MapWorkers:  1,
+		MemoryLimit: 64 * 1024,
+		SpillDir:    t.TempDir(),
+		KeyCodec:    countingCodec[string]{runs: &runs},
+	})
+
+	if err := process.Run(); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+
+	// Contents of strings count towards the limit.
+	if runs := runs.Load(); runs < 10 {
+		t.Errorf("%d runs were written, want at least 10", runs)
+	}
+}
+
+func TestSpillError(t *testing.T) {
+	spillDir := t.TempDir()
+
+	process := meduce.NewDefaultProcess(meduce.Config[int, int, int, int]{
+		Mapper: func(_ int, value int, emit meduce.Emitter[int, int]) {
+			emit(value, 1)
+		},
+		Reducer:     sum,
+		Source:      sources.NewSliceSource(numbers(1000)),
+		Collector:   collectors.NewMapCollector[int, int](),
+		MemoryLimit: 100,
+		PairSize:    func(int, int) int { return 1 },
+		SpillDir:    spillDir,
+		ValueCodec:  failingCodec[int]{},
+	})
+
+	if err := process.Run(); !errors.Is(err, errEncode) {
+		t.Errorf("Run() = %v, want %v", err, errEncode)
+	}
+
+	assertEmptyDir(t, spillDir)
+}
@@ -0,0 +1,194 @@
+package meduce
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MappingThreadStats are statistics of a single mapping thread.
+type MappingThreadStats struct {
+	Mappings     int   // Mappings is the number of records read from Source and mapped
+	BytesRead    int64 // BytesRead is the total length of string and byte slice keys and values read, if Source doesn't report it
+	Emits        int   // Emits is the number of key-value pairs emitted by Mapper
+	Combinations int   // Combinations is the number of pairs left after combining
+	SpilledRuns  int   // SpilledRuns is the number of runs written to temporary files
+
+	// PeakBufferedPairs is the largest number of emitted
+	// pairs that the thread held in memory at once.
+	PeakBufferedPairs int
+
+	MapDuration     time.Duration // MapDuration is the time spent reading and mapping records
+	CombineDuration time.Duration // CombineDuration is the time spent sorting, combining and spilling pairs
+}
+
+// ReducingThreadStats are statistics of a single reducing thread.
+type ReducingThreadStats struct {
+	Reductions  int // Reductions is the number of reduced groups
+	Collections int // Collections is the number of pairs passed to Collector
+	FilteredOut int // FilteredOut is the number of keys discarded by Filter
+
+	ReduceDuration time.Duration // ReduceDuration is the time spent reducing, filtering and collecting
+}
+
+// Stats are statistics of a finished process.
+//
+// Totals are sums of statistics of all threads,
+// except PeakBufferedPairs which is the sum of peaks of
+// mapping threads, as an upper bound of pairs held at once,
+// and BytesRead which is the number of bytes that Source
+// reported as read, if it reports them.
+type Stats struct {
+	MappingThreads  []MappingThreadStats
+	ReducingThreads []ReducingThreadStats
+
+	Mappings          int
+	BytesRead         int64
+	Emits             int
+	Combinations      int
+	SpilledRuns       int
+	PeakBufferedPairs int
+
+	Reductions  int
+	Collections int
+	FilteredOut int
+
+	MapDuration    time.Duration // MapDuration is the duration of the whole map phase, including combining
+	ReduceDuration time.Duration // ReduceDuration is the duration of the whole reduce phase, including merging
+	TotalDuration  time.Duration
+
+	// Counters are values by which user-defined counters
+	// were incremented while the process was running.
+	Counters map[string]int64
+}
+
+// Stats blocks until the process is finished and returns its statistics.
+//
+// If the process was stopped, statistics cover only the work done before that.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) Stats() Stats {
+	process.processFinished.Wait()
+
+	return process.stats
+}
+
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) recordMappingStats(duration time.Duration) {
+	stats := &process.stats
+
+	stats.MapDuration = duration
+	stats.MappingThreads = make([]MappingThreadStats, len(process.mappingThreads))
+
+	for i, thread := range process.mappingThreads {
+		threadStats := thread.stats()
+		stats.MappingThreads[i] = threadStats
+
+		stats.Mappings += threadStats.Mappings
+		stats.BytesRead += threadStats.BytesRead
+		stats.Emits += threadStats.Emits
+		stats.Combinations += threadStats.Combinations
+		stats.SpilledRuns += threadStats.SpilledRuns
+		stats.PeakBufferedPairs += threadStats.PeakBufferedPairs
+	}
+
+	if bytesRead := process.Source.state.BytesRead(); bytesRead > 0 {
+		stats.BytesRead = bytesRead
+	}
+}
+
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) recordReducingStats(duration time.Duration) {
+	stats := &process.stats
+
+	stats.ReduceDuration = duration
+	stats.ReducingThreads = make([]ReducingThreadStats, len(process.reducingThreads))
+
+	for i, thread := range process.reducingThreads {
+		threadStats := thread.stats()
+		stats.ReducingThreads[i] = threadStats
+
+		stats.Reductions += threadStats.Reductions
+		stats.Collections += threadStats.Collections
+		stats.FilteredOut += threadStats.FilteredOut
+	}
+}
+
+// startCounters remembers values of the counters when the process is started,
+// so that counters shared with earlier processes are reported only for this one.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) startCounters() {
+	if process.Counters == nil {
+		return
+	}
+
+	process.countersStart = process.Counters.Values()
+}
+
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) recordCounters() {
+	if process.Counters == nil {
+		return
+	}
+
+	process.stats.Counters = process.Counters.Values()
+	for name, value := range process.countersStart {
+		process.stats.Counters[name] -= value
+	}
+
+	if process.Logger != nil && len(process.stats.Counters) > 0 {
+		names := slices.Sorted(maps.Keys(process.stats.Counters))
+
+		var sb strings.Builder
+
+		sb.WriteString(fmt.Sprintf("Process %s: counters\n", process.label()))
+		for _, name := range names {
+			sb.WriteString(fmt.Sprintf("\t%s: %d\n", name, process.stats.Counters[name]))
+		}
+
+		process.Logger.Print(sb.String())
+	}
+}
+
+func (thread *mappingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) stats() MappingThreadStats {
+	return MappingThreadStats{
+		Mappings:     thread.mappingsCount,
+		BytesRead:    thread.bytesRead,
+		Emits:        thread.emitsCount,
+		Combinations: thread.combinationsCount,
+		SpilledRuns:  len(thread.runs),
+
+		PeakBufferedPairs: thread.peakBufferedPairs,
+
+		MapDuration:     thread.mapDuration,
+		CombineDuration: thread.combineDuration,
+	}
+}
+
+func (thread *reducingThread[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) stats() ReducingThreadStats {
+	return ReducingThreadStats{
+		Reductions:  thread.reductionsCount,
+		Collections: thread.collectionsCount,
+		FilteredOut: thread.filteredCount,
+
+		ReduceDuration: thread.reduceDuration,
+	}
+}
+
+// recordSizer returns a function that measures how many bytes a record occupies,
+// counting only keys and values that are strings or byte slices.
+// If neither of them is, it returns nil and records are not measured.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) recordSizer() func(key KeyIn, value ValueIn) int64 {
+	measureKeys, measureValues := hasLength[KeyIn](), hasLength[ValueIn]()
+	if !measureKeys && !measureValues {
+		return nil
+	}
+
+	return func(key KeyIn, value ValueIn) int64 {
+		var size int
+		if measureKeys {
+			size += byteLength(key)
+		}
+		if measureValues {
+			size += byteLength(value)
+		}
+
+		return int64(size)
+	}
+}
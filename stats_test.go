@@ -0,0 +1,80 @@
+package meduce_test
+
+import (
+	"github.com/djordje200179/meduce"
+	"github.com/djordje200179/meduce/collectors"
+	"github.com/djordje200179/meduce/sources"
+	"strings"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	words := make([]string, 1000)
+	for i := range words {
+		words[i] = strings.Repeat("a", i%10+1)
+	}
+
+	process := meduce.NewDefaultProcess(meduce.Config[int, string, string, int]{
+		Mapper: func(_ int, word string, emit meduce.Emitter[string, int]) {
+			emit(word, 1)
+			emit(word, 1)
+		},
+		Reducer: func(_ string, values []int) int {
+			return sum(0, values)
+		},
+		Filter: func(word string, _ *int) bool {
+			return len(word) > 5
+		},
+		Source:        sources.NewSliceSource(words),
+		Collector:     collectors.NewMapCollector[string, int](),
+		MapWorkers:    2,
+		ReduceWorkers: 3,
+	})
+
+	if err := process.Run(); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+
+	stats := process.Stats()
+
+	if len(stats.MappingThreads) != 2 || len(stats.ReducingThreads) != 3 {
+		t.Errorf("got stats of %d mapping and %d reducing threads, want 2 and 3",
+			len(stats.MappingThreads), len(stats.ReducingThreads))
+	}
+
+	if stats.Mappings != 1000 {
+		t.Errorf("Mappings = %d, want 1000", stats.Mappings)
+	}
+	// Each length from 1 to 10 is used by 100 words.
+	if stats.BytesRead != 5500 {
+		t.Errorf("BytesRead = %d, want 5500", stats.BytesRead)
+	}
+	if stats.Emits != 2000 {
+		t.Errorf("Emits = %d, want 2000", stats.Emits)
+	}
+	if stats.Combinations < 10 || stats.Combinations > 20 {
+		t.Errorf("Combinations = %d, want between 10 and 20", stats.Combinations)
+	}
+	if stats.Reductions != 10 {
+		t.Errorf("Reductions = %d, want 10", stats.Reductions)
+	}
+	if stats.Collections != 5 || stats.FilteredOut != 5 {
+		t.Errorf("Collections = %d and FilteredOut = %d, want 5 and 5", stats.Collections, stats.FilteredOut)
+	}
+
+	var mappings, collections int
+	for _, threadStats := range stats.MappingThreads {
+		mappings += threadStats.Mappings
+	}
+	for _, threadStats := range stats.ReducingThreads {
+		collections += threadStats.Collections
+	}
+	if mappings != stats.Mappings || collections != stats.Collections {
+		t.Errorf("totals %d and %d differ from sums of threads %d and %d",
+			stats.Mappings, stats.Collections, mappings, collections)
+	}
+
+	if stats.TotalDuration < stats.MapDuration {
+		t.Errorf("TotalDuration %v is shorter than MapDuration %v", stats.TotalDuration, stats.MapDuration)
+	}
+}
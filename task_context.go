@@ -0,0 +1,108 @@
+package meduce
+
+import (
+	"context"
+	"log/slog"
+)
+
+// A TaskContext is passed to context-aware user functions.
+// It describes the thread in which the function is called.
+//
+// It is a context.Context that is cancelled when the process is stopped,
+// so long-running functions can check it and return early.
+type TaskContext struct {
+	context.Context
+
+	Thread      int    // Thread is the index of the mapping or reducing thread
+	ProcessUid  int    // ProcessUid is the uid of the process
+	ProcessName string // ProcessName is the name of the process
+
+	Counters *Counters // Counters are counters of the process
+	// Logger is StructuredLogger of the process with attributes
+	// of the thread. If StructuredLogger is not set, it discards logs.
+	Logger *slog.Logger
+
+	sideInputs []SideInputLoader
+	fail       func(err error)
+}
+
+// Counter returns the counter of the process with the given name.
+func (ctx *TaskContext) Counter(name string) *Counter {
+	return ctx.Counters.Counter(name)
+}
+
+// SideInput returns the side input of the process with
+// the given name, or nil if there is no such side input.
+func (ctx *TaskContext) SideInput(name string) SideInputLoader {
+	for _, input := range ctx.sideInputs {
+		if input.Name() == name {
+			return input
+		}
+	}
+
+	return nil
+}
+
+// Fail stops the process with the given error.
+// The function that called it should return as soon as possible.
+func (ctx *TaskContext) Fail(err error) {
+	ctx.fail(err)
+}
+
+// LookupSideInput returns the side input of the process with the
+// given name and types, and reports whether it exists.
+func LookupSideInput[Key comparable, Value any](ctx *TaskContext, name string) (*SideInput[Key, Value], bool) {
+	input, ok := ctx.SideInput(name).(*SideInput[Key, Value])
+	return input, ok
+}
+
+// A MapperCtx is a Mapper that also receives the context of its thread.
+type MapperCtx[KeyIn, ValueIn, KeyOut, ValueOut any] func(ctx *TaskContext, key KeyIn, value ValueIn, emit Emitter[KeyOut, ValueOut])
+
+// An AggregatorCtx is an Aggregator that also receives the context of its thread.
+type AggregatorCtx[KeyOut, ValueMid, ValueOut any] func(ctx *TaskContext, key KeyOut, values []ValueMid) ValueOut
+
+// A ReducerCtx is a Reducer that also receives the context of its thread.
+type ReducerCtx[KeyOut, ValueOut any] = AggregatorCtx[KeyOut, ValueOut, ValueOut]
+
+// AdaptMapper converts a Mapper to a MapperCtx that ignores its context.
+func AdaptMapper[KeyIn, ValueIn, KeyOut, ValueOut any](mapper Mapper[KeyIn, ValueIn, KeyOut, ValueOut]) MapperCtx[KeyIn, ValueIn, KeyOut, ValueOut] {
+	return func(_ *TaskContext, key KeyIn, value ValueIn, emit Emitter[KeyOut, ValueOut]) {
+		mapper(key, value, emit)
+	}
+}
+
+// AdaptAggregator converts an Aggregator (or a Reducer)
+// to an AggregatorCtx that ignores its context.
+func AdaptAggregator[KeyOut, ValueMid, ValueOut any](aggregator Aggregator[KeyOut, ValueMid, ValueOut]) AggregatorCtx[KeyOut, ValueMid, ValueOut] {
+	return func(_ *TaskContext, key KeyOut, values []ValueMid) ValueOut {
+		return aggregator(key, values)
+	}
+}
+
+// newTaskContext creates the context of a mapping or reducing thread.
+func (process *AggregationProcess[KeyIn, ValueIn, KeyOut, ValueMid, ValueOut]) newTaskContext(ctx context.Context, phase Phase, thread int) TaskContext {
+	logger := process.StructuredLogger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+
+	return TaskContext{
+		Context: ctx,
+
+		Thread:      thread,
+		ProcessUid:  process.uid,
+		ProcessName: process.Name,
+
+		Counters: process.Counters,
+		Logger: logger.With(
+			slog.Int("uid", process.uid),
+			slog.String("name", process.Name),
+			slog.String("phase", phase.String()),
+			slog.Int("thread", thread),
+		),
+
+		sideInputs: process.SideInputs,
+		fail:       process.fail,
+	}
+}
@@ -0,0 +1,110 @@
+package meduce_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/djordje200179/meduce"
+	"github.com/djordje200179/meduce/collectors"
+	"github.com/djordje200179/meduce/sources"
+	"log/slog"
+	"sync"
+	"testing"
+)
+
+func TestTaskContext(t *testing.T) {
+	parities := meduce.NewSideInput("parities", sources.NewSliceSource([]string{"even", "odd"}))
+
+	var mutex sync.Mutex
+	threads := make(map[int]bool)
+	var output bytes.Buffer
+
+	collector := collectors.NewMapCollector[string, int]()
+	process := meduce.NewDefaultProcess(meduce.Config[int, int, string, int]{
+		Name: "parities",
+		MapperCtx: func(ctx *meduce.TaskContext, _ int, value int, emit meduce.Emitter[string, int]) {
+			mutex.Lock()
+			threads[ctx.Thread] = true
+			mutex.Unlock()
+
+			if ctx.ProcessName != "parities" {
+				t.Errorf("ProcessName = %q, want %q", ctx.ProcessName, "parities")
+			}
+
+			input, ok := meduce.LookupSideInput[int, string](ctx, "parities")
+			if !ok {
+				t.Fatal("side input was not found")
+			}
+
+			ctx.Counter("records").Inc()
+
+			name, _ := input.Get(value % 2)
+			emit(name, 1)
+		},
+		ReducerCtx: func(ctx *meduce.TaskContext, key string, values []int) int {
+			ctx.Logger.Info("reduced", "key", key)
+			return sum(0, values)
+		},
+		SideInputs:       []meduce.SideInputLoader{parities},
+		Source:           sources.NewSliceSource(numbers(1000)),
+		Collector:        collector,
+		DisableCombining: true,
+		MapWorkers:       3,
+		StructuredLogger: slog.New(slog.NewJSONHandler(&output, nil)),
+	})
+
+	if err := process.Run(); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+
+	if collector["even"] != 500 || collector["odd"] != 500 {
+		t.Errorf("collected %v, want 500 even and 500 odd numbers", collector)
+	}
+
+	for thread := range threads {
+		if thread < 0 || thread >= 3 {
+			t.Errorf("mapper was called from thread %d, want one of 3 threads", thread)
+		}
+	}
+
+	// The process creates its own counters, as they were not set.
+	if records := process.Stats().Counters["records"]; records != 1000 {
+		t.Errorf("counter records = %d, want 1000", records)
+	}
+
+	var reduced int
+	for _, event := range logEvents(t, &output) {
+		if event["msg"] == "reduced" {
+			reduced++
+			if event["name"] != "parities" || event["phase"] != "reduce" {
+				t.Errorf("reducer logged %v, want attributes of the process and phase", event)
+			}
+		}
+	}
+	if reduced != 2 {
+		t.Errorf("reducer logged %d times, want 2", reduced)
+	}
+}
+
+func TestTaskContextFail(t *testing.T) {
+	errInvalid := errors.New("invalid record")
+
+	process := meduce.NewDefaultProcess(meduce.Config[int, int, int, int]{
+		MapperCtx: func(ctx *meduce.TaskContext, _ int, value int, emit meduce.Emitter[int, int]) {
+			if value == 10 {
+				ctx.Fail(errInvalid)
+
+				<-ctx.Done()
+				return
+			}
+
+			emit(value%10, 1)
+		},
+		Reducer:   sum,
+		Source:    sources.NewSliceSource(numbers(1000)),
+		Collector: collectors.NewMapCollector[int, int](),
+	})
+
+	if err := process.Run(); !errors.Is(err, errInvalid) {
+		t.Errorf("Run() = %v, want %v", err, errInvalid)
+	}
+}
@@ -1,14 +1,5 @@
 package meduce
 
-import "github.com/djordje200179/extendedlibrary/misc"
-
-// A Source is a channel that is created by user
-// and from which key-value pairs are read.
-//
-// Preferably, it should be buffered to avoid
-// blocking and context switches.
-type Source[KeyIn, ValueIn any] <-chan misc.Pair[KeyIn, ValueIn]
-
 // An Emitter is a function that is supplied by library.
 //
 // It is passed to user's Mapper function,
@@ -24,6 +15,15 @@ type Emitter[KeyOut, ValueOut any] func(key KeyOut, value ValueOut)
 // to emit any number of key-value pairs.
 type Mapper[KeyIn, ValueIn, KeyOut, ValueOut any] func(key KeyIn, value ValueIn, emit Emitter[KeyOut, ValueOut])
 
+// An Aggregator is a function that is created by user
+// and is used to reduce all mapped values of a key
+// to a single value of a possibly different type.
+//
+// It is called once for each key, with all values for that key.
+//
+// It should have no side effects.
+type Aggregator[KeyOut, ValueMid, ValueOut any] func(key KeyOut, values []ValueMid) ValueOut
+
 // A Reducer is a function that is created by user
 // and is used to reduce values to single value.
 //
@@ -31,7 +31,16 @@ type Mapper[KeyIn, ValueIn, KeyOut, ValueOut any] func(key KeyIn, value ValueIn,
 // until all values for that key are reduced.
 //
 // It should be idempotent and have no side effects.
-type Reducer[KeyOut, ValueOut any] func(key KeyOut, values []ValueOut) ValueOut
+type Reducer[KeyOut, ValueOut any] = Aggregator[KeyOut, ValueOut, ValueOut]
+
+// A Combiner is a function that is created by user
+// and is used to partially reduce values in mapping threads,
+// before they are merged and passed to the Reducer.
+//
+// It is called multiple times for each key, with values
+// emitted by a single mapping thread, so it should be
+// associative and have no side effects.
+type Combiner[KeyOut, ValueOut any] func(key KeyOut, values []ValueOut) ValueOut
 
 // A Finalizer is a function that is created by user
 // and is used to finalize key-value pairs.
@@ -54,8 +63,24 @@ type Filter[KeyOut, ValueOut any] func(key KeyOut, valueRef *ValueOut) bool
 
 // A Collector is an entity that is supplied by user
 // and is used to collect processed key-value pairs.
+//
+// If any of the methods returns an error, the process is stopped
+// and the error is returned from Run.
+// Finalize is called whenever Init succeeded, even if the process failed.
 type Collector[KeyOut, ValueOut any] interface {
-	Init()                              // Init is called just before collecting starts
-	Collect(key KeyOut, value ValueOut) // Collect is called for each processed key-value pair
-	Finalize()                          // Finalize is called after all key-value pairs were processed
+	Init() error                              // Init is called just before collecting starts
+	Collect(key KeyOut, value ValueOut) error // Collect is called for each processed key-value pair
+	Finalize() error                          // Finalize is called after all key-value pairs were processed
+}
+
+// A ResumableCollector is a collector whose output survives restarts
+// of a process, like a file. Processes with CheckpointDir use it
+// to continue collecting where a failed run stopped.
+type ResumableCollector interface {
+	// Checkpoint makes all collected pairs durable
+	// and returns the current position in the output.
+	Checkpoint() (int64, error)
+	// Resume discards all pairs collected after the given position.
+	// It is called after Init, with 0 if there is nothing to resume.
+	Resume(position int64) error
 }